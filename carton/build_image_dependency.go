@@ -38,13 +38,14 @@ type BuildImageDependency struct {
 func (i BuildImageDependency) Update(options ...Option) {
 	config := Config{
 		exitHandler: utils.NewExitHandler(),
+		progress:    os.Stderr,
 	}
 
 	for _, option := range options {
 		config = option(config)
 	}
 
-	logger := log.NewPaketoLogger(os.Stdout)
+	logger := log.NewPaketoLogger(config.progress)
 	_, _ = fmt.Fprintf(logger.TitleWriter(), "\n%s\n", log.FormatIdentity("Build Image", i.Version))
 
 	c, err := os.ReadFile(i.BuilderPath)
@@ -0,0 +1,204 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package carton
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// BuilderChange is a single difference between two builder.toml files.
+type BuilderChange struct {
+
+	// Kind is what changed, one of `buildpack`, `lifecycle`, `build-image` or `run-image`.
+	Kind string `json:"kind"`
+
+	// Name identifies the changed item, e.g. a buildpack id.
+	Name string `json:"name"`
+
+	// Status is one of added, removed or changed.
+	Status string `json:"status"`
+
+	// Old is the previous version or image reference.
+	Old string `json:"old,omitempty"`
+
+	// New is the new version or image reference.
+	New string `json:"new,omitempty"`
+}
+
+// BuilderDiff compares the buildpacks, lifecycle and images of two builder.toml files.
+type BuilderDiff struct {
+	OldPath string
+	NewPath string
+}
+
+type builderContents struct {
+	buildpacks  map[string]string
+	lifecycle   string
+	buildImages map[string]string
+	runImages   map[string]string
+}
+
+var lifecycleVersionExp = regexp.MustCompile(`/v?(\d+\.\d+\.\d+[^/]*)/`)
+
+// Diff returns the changes between the old and new builder.toml, ordered by kind and name.
+func (d BuilderDiff) Diff() ([]BuilderChange, error) {
+	o, err := readBuilder(d.OldPath)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := readBuilder(d.NewPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []BuilderChange
+	changes = append(changes, diffVersions("buildpack", o.buildpacks, n.buildpacks)...)
+	changes = append(changes, diffVersions("lifecycle", map[string]string{"lifecycle": o.lifecycle}, map[string]string{"lifecycle": n.lifecycle})...)
+	changes = append(changes, diffVersions("build-image", o.buildImages, n.buildImages)...)
+	changes = append(changes, diffVersions("run-image", o.runImages, n.runImages)...)
+
+	return changes, nil
+}
+
+func diffVersions(kind string, o map[string]string, n map[string]string) []BuilderChange {
+	names := map[string]bool{}
+	for name, v := range o {
+		if v != "" {
+			names[name] = true
+		}
+	}
+	for name, v := range n {
+		if v != "" {
+			names[name] = true
+		}
+	}
+
+	var changes []BuilderChange
+	for _, name := range sortedKeys(names) {
+		c := BuilderChange{Kind: kind, Name: name, Old: o[name], New: n[name]}
+		switch {
+		case c.Old == "":
+			c.Status = ChangeAdded
+		case c.New == "":
+			c.Status = ChangeRemoved
+		case c.Old != c.New:
+			c.Status = ChangeChanged
+		default:
+			continue
+		}
+		changes = append(changes, c)
+	}
+
+	return changes
+}
+
+func readBuilder(path string) (builderContents, error) {
+	b := builderContents{
+		buildpacks:  map[string]string{},
+		buildImages: map[string]string{},
+		runImages:   map[string]string{},
+	}
+
+	var raw struct {
+		Buildpacks []struct {
+			ID      string `toml:"id"`
+			URI     string `toml:"uri"`
+			Version string `toml:"version"`
+		} `toml:"buildpacks"`
+		Lifecycle struct {
+			URI     string `toml:"uri"`
+			Version string `toml:"version"`
+		} `toml:"lifecycle"`
+		Stack struct {
+			BuildImage string `toml:"build-image"`
+			RunImage   string `toml:"run-image"`
+		} `toml:"stack"`
+		Build struct {
+			Image string `toml:"image"`
+		} `toml:"build"`
+		Run struct {
+			Images []struct {
+				Image string `toml:"image"`
+			} `toml:"images"`
+		} `toml:"run"`
+	}
+
+	c, err := os.ReadFile(path)
+	if err != nil {
+		return b, fmt.Errorf("unable to read %s\n%w", path, err)
+	}
+
+	if err := toml.Unmarshal(c, &raw); err != nil {
+		return b, fmt.Errorf("unable to decode %s\n%w", path, err)
+	}
+
+	for _, bp := range raw.Buildpacks {
+		ref := ParseImageReference(bp.URI)
+
+		name := bp.ID
+		if name == "" {
+			name = ref.Repository
+		}
+
+		version := bp.Version
+		if version == "" {
+			version = ref.Version()
+		}
+
+		b.buildpacks[name] = version
+	}
+
+	b.lifecycle = raw.Lifecycle.Version
+	if b.lifecycle == "" {
+		if m := lifecycleVersionExp.FindStringSubmatch(raw.Lifecycle.URI); m != nil {
+			b.lifecycle = m[1]
+		} else {
+			b.lifecycle = raw.Lifecycle.URI
+		}
+	}
+
+	for _, image := range []string{raw.Stack.BuildImage, raw.Build.Image} {
+		addImage(b.buildImages, image)
+	}
+
+	addImage(b.runImages, raw.Stack.RunImage)
+	for _, image := range raw.Run.Images {
+		addImage(b.runImages, image.Image)
+	}
+
+	return b, nil
+}
+
+func addImage(images map[string]string, image string) {
+	if image == "" {
+		return
+	}
+
+	ref := ParseImageReference(image)
+	images[ref.Repository] = ref.Version()
+}
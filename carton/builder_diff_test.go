@@ -0,0 +1,113 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package carton_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libpak-tools/carton"
+)
+
+func testBuilderDiff(t *testing.T, _ spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		oldPath string
+		newPath string
+	)
+
+	it.Before(func() {
+		dir := t.TempDir()
+		oldPath = filepath.Join(dir, "old-builder.toml")
+		newPath = filepath.Join(dir, "new-builder.toml")
+	})
+
+	it("reports buildpack, lifecycle and image changes", func() {
+		Expect(os.WriteFile(oldPath, []byte(`
+[[buildpacks]]
+  uri = "docker://gcr.io/paketo-buildpacks/java:1.2.3"
+
+[[buildpacks]]
+  uri = "docker://gcr.io/paketo-buildpacks/go:4.5.6"
+
+[[buildpacks]]
+  uri = "docker://registry:5000/paketo-buildpacks/nodejs:1.0.0"
+
+[lifecycle]
+  uri = "https://github.com/buildpacks/lifecycle/releases/download/v0.17.0/lifecycle-v0.17.0+linux.x86-64.tgz"
+
+[stack]
+  id = "io.buildpacks.stacks.jammy"
+  build-image = "docker.io/paketobuildpacks/build-jammy-base:0.1.1"
+  run-image = "paketobuildpacks/run-jammy-base:latest"
+`), 0600)).To(Succeed())
+
+		Expect(os.WriteFile(newPath, []byte(`
+[[buildpacks]]
+  uri = "docker://gcr.io/paketo-buildpacks/java:1.2.4"
+
+[[buildpacks]]
+  uri = "docker://registry:5000/paketo-buildpacks/nodejs:1.0.0"
+
+[[buildpacks]]
+  id = "paketo-buildpacks/ruby"
+  uri = "docker://gcr.io/paketo-buildpacks/ruby@sha256:abc"
+
+[lifecycle]
+  version = "0.18.0"
+
+[build]
+  image = "docker.io/paketobuildpacks/build-jammy-base:0.1.2"
+
+[[run.images]]
+  image = "paketobuildpacks/run-jammy-base:latest"
+`), 0600)).To(Succeed())
+
+		changes, err := carton.BuilderDiff{OldPath: oldPath, NewPath: newPath}.Diff()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes).To(Equal([]carton.BuilderChange{
+			{Kind: "buildpack", Name: "gcr.io/paketo-buildpacks/go", Status: carton.ChangeRemoved, Old: "4.5.6"},
+			{Kind: "buildpack", Name: "gcr.io/paketo-buildpacks/java", Status: carton.ChangeChanged, Old: "1.2.3", New: "1.2.4"},
+			{Kind: "buildpack", Name: "paketo-buildpacks/ruby", Status: carton.ChangeAdded, New: "sha256:abc"},
+			{Kind: "lifecycle", Name: "lifecycle", Status: carton.ChangeChanged, Old: "0.17.0", New: "0.18.0"},
+			{Kind: "build-image", Name: "docker.io/paketobuildpacks/build-jammy-base", Status: carton.ChangeChanged, Old: "0.1.1", New: "0.1.2"},
+		}))
+	})
+
+	it("reports no changes for identical files", func() {
+		contents := []byte(`
+[[buildpacks]]
+  uri = "docker://gcr.io/paketo-buildpacks/java:1.2.3"
+`)
+		Expect(os.WriteFile(oldPath, contents, 0600)).To(Succeed())
+		Expect(os.WriteFile(newPath, contents, 0600)).To(Succeed())
+
+		changes, err := carton.BuilderDiff{OldPath: oldPath, NewPath: newPath}.Diff()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changes).To(BeEmpty())
+	})
+
+	it("fails if a file is missing", func() {
+		_, err := carton.BuilderDiff{OldPath: oldPath, NewPath: newPath}.Diff()
+		Expect(err).To(MatchError(ContainSubstring("unable to read")))
+	})
+}
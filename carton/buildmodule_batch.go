@@ -0,0 +1,175 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package carton
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/paketo-buildpacks/libpak-tools/internal"
+)
+
+// BuildModuleBatch applies several dependency updates to a build module, which is read and written once. The build
+// module is only written if every update succeeds, and an update fails if it matches no dependency.
+type BuildModuleBatch struct {
+	BuildModulePath string
+
+	// TOMLIndent is the number of spaces nested tables are indented with, when zero the encoder default is used
+	TOMLIndent int
+}
+
+// BuildModuleUpdate is one `[[updates]]` entry of an updates file. Arch defaults to amd64, PURL and CPE default to
+// Version and PURLPattern and CPEPattern default to VersionPattern, like the flags of `dependency update build-module`.
+type BuildModuleUpdate struct {
+	ID             string `toml:"id"`
+	Arch           string `toml:"arch"`
+	Version        string `toml:"version"`
+	VersionPattern string `toml:"version-pattern"`
+	URI            string `toml:"uri"`
+	SHA256         string `toml:"sha256"`
+	PURL           string `toml:"purl"`
+	PURLPattern    string `toml:"purl-pattern"`
+	CPE            string `toml:"cpe"`
+	CPEPattern     string `toml:"cpe-pattern"`
+}
+
+// BuildModuleUpdateResult is the outcome of applying one BuildModuleUpdate
+type BuildModuleUpdateResult struct {
+	ID      string
+	Arch    string
+	Version string
+	Err     error
+}
+
+// ReadBuildModuleUpdates reads the `[[updates]]` of an updates file
+func ReadBuildModuleUpdates(path string) ([]BuildModuleUpdate, error) {
+	var file struct {
+		Updates []BuildModuleUpdate `toml:"updates"`
+	}
+	if _, err := toml.DecodeFile(path, &file); err != nil {
+		return nil, fmt.Errorf("unable to decode updates %s\n%w", path, err)
+	}
+
+	if len(file.Updates) == 0 {
+		return nil, fmt.Errorf("%s has no [[updates]]", path)
+	}
+
+	return file.Updates, nil
+}
+
+// Apply applies each update in turn, continuing past failures so that all of them are reported. The build module is
+// left unchanged if any update fails.
+func (b BuildModuleBatch) Apply(updates []BuildModuleUpdate) ([]BuildModuleUpdateResult, error) {
+	c, err := os.ReadFile(b.BuildModulePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s\n%w", b.BuildModulePath, err)
+	}
+
+	var results []BuildModuleUpdateResult
+	failed := 0
+	for _, u := range updates {
+		d := u.dependency(b)
+		result := BuildModuleUpdateResult{ID: d.ID, Arch: d.Arch, Version: d.Version}
+
+		if err := u.validate(); err != nil {
+			result.Err = err
+		} else if out, err := d.UpdateBytes(c); err != nil {
+			result.Err = err
+		} else {
+			c = out
+		}
+
+		if result.Err != nil {
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	if failed > 0 {
+		return results, fmt.Errorf("%d of %d updates failed, %s was not changed", failed, len(updates), b.BuildModulePath)
+	}
+
+	if err := internal.WriteTOMLFile(b.BuildModulePath, c, internal.TOMLOptions{Indent: b.TOMLIndent}); err != nil {
+		return results, err
+	}
+
+	return results, nil
+}
+
+// validate fails if a field without a default is missing
+func (u BuildModuleUpdate) validate() error {
+	for _, f := range []struct {
+		name  string
+		value string
+	}{
+		{"id", u.ID},
+		{"version", u.Version},
+		{"version-pattern", u.VersionPattern},
+		{"uri", u.URI},
+		{"sha256", u.SHA256},
+	} {
+		if f.value == "" {
+			return fmt.Errorf("%s must be set", f.name)
+		}
+	}
+
+	return nil
+}
+
+// dependency returns the BuildModuleDependency that applies the update, with the defaults filled in
+func (u BuildModuleUpdate) dependency(b BuildModuleBatch) BuildModuleDependency {
+	d := BuildModuleDependency{
+		BuildModulePath: b.BuildModulePath,
+		TOMLIndent:      b.TOMLIndent,
+		ID:              u.ID,
+		Arch:            u.Arch,
+		Version:         u.Version,
+		VersionPattern:  u.VersionPattern,
+		URI:             u.URI,
+		SHA256:          u.SHA256,
+		PURL:            u.PURL,
+		PURLPattern:     u.PURLPattern,
+		CPE:             u.CPE,
+		CPEPattern:      u.CPEPattern,
+		NormalizeArch:   true,
+		RequireMatch:    true,
+	}
+
+	if d.Arch == "" {
+		d.Arch = "amd64"
+	}
+
+	if d.PURL == "" {
+		d.PURL = d.Version
+	}
+
+	if d.PURLPattern == "" {
+		d.PURLPattern = d.VersionPattern
+	}
+
+	if d.CPE == "" {
+		d.CPE = d.Version
+	}
+
+	if d.CPEPattern == "" {
+		d.CPEPattern = d.VersionPattern
+	}
+
+	return d
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package carton_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	libpakTesting "github.com/paketo-buildpacks/libpak/v2/testing"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libpak-tools/carton"
+)
+
+func testBuildModuleBatch(t *testing.T, _ spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		path     string
+		original = []byte(`api = "0.7"
+[buildpack]
+id = "some-buildpack"
+
+[[metadata.dependencies]]
+id      = "one"
+version = "1.0.0"
+uri     = "one-uri-1"
+sha256  = "one-sha256-1"
+stacks  = ["test-stack"]
+
+[[metadata.dependencies]]
+id      = "two"
+version = "2.0.0"
+uri     = "two-uri-1"
+sha256  = "two-sha256-1"
+stacks  = ["test-stack"]
+`)
+	)
+
+	it.Before(func() {
+		path = filepath.Join(t.TempDir(), "buildpack.toml")
+		Expect(os.WriteFile(path, original, 0600)).To(Succeed())
+	})
+
+	it("reads the updates of an updates file", func() {
+		updatesPath := filepath.Join(t.TempDir(), "updates.toml")
+		Expect(os.WriteFile(updatesPath, []byte(`[[updates]]
+id              = "one"
+arch            = "arm64"
+version         = "1.0.1"
+version-pattern = '^1\.0\.\d+$'
+uri             = "one-uri-2"
+sha256          = "one-sha256-2"
+purl            = "1.0.1"
+cpe             = "1.0.1"
+`), 0600)).To(Succeed())
+
+		Expect(carton.ReadBuildModuleUpdates(updatesPath)).To(Equal([]carton.BuildModuleUpdate{
+			{
+				ID:             "one",
+				Arch:           "arm64",
+				Version:        "1.0.1",
+				VersionPattern: `^1\.0\.\d+$`,
+				URI:            "one-uri-2",
+				SHA256:         "one-sha256-2",
+				PURL:           "1.0.1",
+				CPE:            "1.0.1",
+			},
+		}))
+	})
+
+	it("fails when an updates file has no updates", func() {
+		updatesPath := filepath.Join(t.TempDir(), "updates.toml")
+		Expect(os.WriteFile(updatesPath, []byte(""), 0600)).To(Succeed())
+
+		_, err := carton.ReadBuildModuleUpdates(updatesPath)
+		Expect(err).To(MatchError(ContainSubstring("has no [[updates]]")))
+	})
+
+	it("applies every update", func() {
+		results, err := carton.BuildModuleBatch{BuildModulePath: path}.Apply([]carton.BuildModuleUpdate{
+			{ID: "one", Version: "1.0.1", VersionPattern: `^1\.0\.\d+$`, URI: "one-uri-2", SHA256: "one-sha256-2"},
+			{ID: "two", Version: "2.0.1", VersionPattern: `^2\.0\.\d+$`, URI: "two-uri-2", SHA256: "two-sha256-2"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(results).To(Equal([]carton.BuildModuleUpdateResult{
+			{ID: "one", Arch: "amd64", Version: "1.0.1"},
+			{ID: "two", Arch: "amd64", Version: "2.0.1"},
+		}))
+
+		Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`api = "0.7"
+[buildpack]
+id = "some-buildpack"
+
+[[metadata.dependencies]]
+id      = "one"
+version = "1.0.1"
+uri     = "one-uri-2"
+sha256  = "one-sha256-2"
+stacks  = ["test-stack"]
+
+[[metadata.dependencies]]
+id      = "two"
+version = "2.0.1"
+uri     = "two-uri-2"
+sha256  = "two-sha256-2"
+stacks  = ["test-stack"]
+`))
+	})
+
+	it("fails an update that matches no dependency and leaves the build module unchanged", func() {
+		results, err := carton.BuildModuleBatch{BuildModulePath: path}.Apply([]carton.BuildModuleUpdate{
+			{ID: "one", Version: "1.0.1", VersionPattern: `^1\.0\.\d+$`, URI: "one-uri-2", SHA256: "one-sha256-2"},
+			{ID: "tow", Version: "2.0.1", VersionPattern: `^2\.0\.\d+$`, URI: "two-uri-2", SHA256: "two-sha256-2"},
+		})
+		Expect(err).To(MatchError(ContainSubstring("1 of 2 updates failed")))
+		Expect(results[0].Err).NotTo(HaveOccurred())
+		Expect(results[1].Err).To(MatchError(ContainSubstring("no tow dependency matched arch amd64")))
+
+		Expect(os.ReadFile(path)).To(Equal(original))
+	})
+
+	it("reports every failure and leaves the build module unchanged", func() {
+		results, err := carton.BuildModuleBatch{BuildModulePath: path}.Apply([]carton.BuildModuleUpdate{
+			{ID: "one", Version: "1.0.1", VersionPattern: `^1\.0\.\d+$`, URI: "one-uri-2"},
+			{ID: "two", Version: "2.0.1", VersionPattern: `^2\.0\.\d+$`, URI: "two-uri-2", SHA256: "two-sha256-2"},
+			{ID: "three", Version: "3.0.1", VersionPattern: `^3\.(`, URI: "three-uri-2", SHA256: "three-sha256-2"},
+		})
+		Expect(err).To(MatchError(ContainSubstring("2 of 3 updates failed")))
+		Expect(results).To(HaveLen(3))
+		Expect(results[0].Err).To(MatchError("sha256 must be set"))
+		Expect(results[1].Err).NotTo(HaveOccurred())
+		Expect(results[2].Err).To(MatchError(ContainSubstring("unable to compile version regex")))
+
+		Expect(os.ReadFile(path)).To(Equal(original))
+	})
+}
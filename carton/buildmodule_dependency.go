@@ -17,12 +17,18 @@
 package carton
 
 import (
-	"bytes"
+	"cmp"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"regexp"
+	"slices"
+	"sort"
+	"strings"
 
-	"github.com/BurntSushi/toml"
+	"github.com/Masterminds/semver/v3"
+	"github.com/package-url/packageurl-go"
 
 	"github.com/paketo-buildpacks/libpak/v2/log"
 	"github.com/paketo-buildpacks/libpak/v2/utils"
@@ -33,6 +39,8 @@ import (
 const (
 	BuildModuleDependencyPattern      = `(?m)([\s]*.*id[\s]+=[\s]+"%s"\n.*\n[\s]*version[\s]+=[\s]+")%s("\n[\s]*uri[\s]+=[\s]+").*("\n[\s]*sha256[\s]+=[\s]+").*(".*)`
 	BuildModuleDependencySubstitution = "${1}%s${2}%s${3}%s${4}"
+
+	DefaultChecksumAlgorithm = "sha256"
 )
 
 type BuildModuleDependency struct {
@@ -50,195 +58,738 @@ type BuildModuleDependency struct {
 	Source          string
 	SourceSHA256    string
 	EolID           string
+
+	// EolCache is the path to a JSON cache of release cycles that is read before, and updated after, looking up EOL
+	// dates on endoflife.date
+	EolCache string
+
+	// ArchValues holds a URI and SHA256 per arch, so that all arches of a dependency can be updated at once. When
+	// set, it is used instead of Arch, URI and SHA256.
+	ArchValues map[string]ArchValue
+
+	// NormalizeArch maps common alternate arch spellings, like x86_64 and aarch64, to their CNB names before matching
+	NormalizeArch bool
+
+	// ArchAliases maps additional arch spellings to CNB arch names before matching
+	ArchAliases map[string]string
+
+	// BuildNumber is the new build number for dependencies that keep it apart from the version, it is written to an
+	// existing `revision` key or to `build`
+	BuildNumber string
+
+	// Algorithm is the algorithm of the SHA256 and SourceSHA256 digests. When empty, the algorithm already used by
+	// a matching dependency's `checksum` is kept, otherwise it defaults to sha256.
+	Algorithm string
+
+	// ExactVersion treats VersionPattern as a literal version that must match the whole version of a dependency,
+	// rather than as a regular expression
+	ExactVersion bool
+
+	// NotifyWebhook is a URL that is sent a DependencyUpdateNotification when a dependency is changed. Failing to
+	// notify is logged but does not fail the update.
+	NotifyWebhook string
+
+	// TOMLIndent is the number of spaces nested tables are indented with, when zero the encoder default is used
+	TOMLIndent int
+
+	// VersionConstraint is a SemVer range, like `>=17.0.0 <18.0.0`, that selects the dependencies to update instead of
+	// VersionPattern. Dependencies whose version is not SemVer are skipped with a warning.
+	VersionConstraint string
+
+	// MatchURI selects the single dependency with this uri instead of matching its version against VersionPattern.
+	// It is an error if more than one dependency has the uri.
+	MatchURI string
+
+	// MetadataSubkey selects dependencies grouped in a `[[metadata.dependencies.<subkey>]]` subsection instead of the
+	// flat `[[metadata.dependencies]]` array
+	MetadataSubkey string
+
+	// Quiet suppresses the summary of the update that is logged before it is applied, warnings and errors are still
+	// reported
+	Quiet bool
+
+	// CheckURI confirms that the new URIs and Source are reachable, without downloading them, before the build module
+	// is rewritten
+	CheckURI bool
+
+	// Backup copies the build module to <path>.bak before it is rewritten, the copy is kept if the write fails
+	Backup bool
+
+	// FailOnNoChange fails the update when the build module is byte-identical after it is applied
+	FailOnNoChange bool
+
+	// AddIfMissing appends a new dependency for each updated arch that no existing dependency matched
+	AddIfMissing bool
+
+	// RequireMatch fails the update when no dependency matches the id, arch and version pattern
+	RequireMatch bool
+
+	// Stacks replaces the stacks of each updated dependency, e.g. when a new version is built for another stack. The
+	// stacks are left untouched when Stacks is empty.
+	Stacks []string
+
+	// Name replaces the part of the dependency name matched by NamePattern, or the current version if NamePattern is
+	// empty. The name is left untouched when Name is empty.
+	Name        string
+	NamePattern string
+}
+
+// DependencyUpdateNotification is the JSON payload posted to NotifyWebhook.
+type DependencyUpdateNotification struct {
+	ID         string `json:"id"`
+	OldVersion string `json:"old_version"`
+	NewVersion string `json:"new_version"`
+	File       string `json:"file"`
+}
+
+// ArchValue is the arch specific part of a dependency update.
+type ArchValue struct {
+	URI    string
+	SHA256 string
 }
 
 func (b BuildModuleDependency) Update(options ...Option) {
 	config := Config{
 		exitHandler: utils.NewExitHandler(),
+		progress:    os.Stderr,
 	}
 
 	for _, option := range options {
 		config = option(config)
 	}
 
-	logger := log.NewPaketoLogger(os.Stdout)
-	_, _ = fmt.Fprintf(logger.TitleWriter(), "\n%s\n", log.FormatIdentity(b.ID, b.VersionPattern))
-	logger.Headerf("Arch:         %s", b.Arch)
-	logger.Headerf("Version:      %s", b.Version)
-	logger.Headerf("PURL:         %s", b.PURL)
-	logger.Headerf("CPEs:         %s", b.CPE)
-	logger.Headerf("URI:          %s", b.URI)
-	logger.Headerf("SHA256:       %s", b.SHA256)
-	logger.Headerf("Source:       %s", b.Source)
-	logger.Headerf("SourceSHA256: %s", b.SourceSHA256)
-	logger.Headerf("EOL ID:       %s", b.EolID)
+	logger := log.NewPaketoLogger(config.progress)
+	if !b.Quiet {
+		b.logSummary(logger)
+	}
 
-	versionExp, err := regexp.Compile(b.VersionPattern)
+	if b.MatchURI == "" && b.VersionConstraint == "" && !b.ExactVersion && !IsAnchoredPattern(b.VersionPattern) {
+		logger.Headerf("Warning: version pattern %q is not anchored with ^ and $ and may match unintended versions", b.VersionPattern)
+	}
+
+	in, err := os.ReadFile(b.BuildModulePath)
 	if err != nil {
-		config.exitHandler.Error(fmt.Errorf("unable to compile version regex %s\n%w", b.VersionPattern, err))
+		config.exitHandler.Error(fmt.Errorf("unable to read %s\n%w", b.BuildModulePath, err))
 		return
 	}
 
-	cpeExp, err := regexp.Compile(b.CPEPattern)
+	out, notification, err := b.update(in, logger)
 	if err != nil {
-		config.exitHandler.Error(fmt.Errorf("unable to compile cpe regex %s\n%w", b.CPEPattern, err))
+		config.exitHandler.Error(err)
 		return
 	}
 
-	purlExp, err := regexp.Compile(b.PURLPattern)
-	if err != nil {
-		config.exitHandler.Error(fmt.Errorf("unable to compile cpe regex %s\n%w", b.PURLPattern, err))
+	if b.CheckURI {
+		for _, uri := range b.uris() {
+			status, err := internal.CheckURI(uri)
+			if err != nil {
+				config.exitHandler.Error(fmt.Errorf("unable to verify uri\n%w", err))
+				return
+			}
+			if !b.Quiet {
+				logger.Headerf("Checked:      %s (%d)", uri, status)
+			}
+		}
+	}
+
+	if err := internal.WriteTOMLFile(b.BuildModulePath, out, b.tomlOptions()); err != nil {
+		config.exitHandler.Error(err)
 		return
 	}
 
-	c, err := os.ReadFile(b.BuildModulePath)
+	if b.NotifyWebhook != "" && notification != nil {
+		if err := internal.PostWebhook(b.NotifyWebhook, notification); err != nil {
+			logger.Headerf("Warning: unable to notify webhook\n%s", err)
+		}
+	}
+}
+
+// UpdateBytes applies the same update as Update to the build module in, without reading or writing a file, and returns
+// the result. Nothing is logged, URIs are not checked and no webhook is notified.
+func (b BuildModuleDependency) UpdateBytes(in []byte) ([]byte, error) {
+	out, _, err := b.update(in, log.NewPaketoLogger(io.Discard))
+	return out, err
+}
+
+// update applies the dependency update to the build module in and returns the result, along with a notification if a
+// dependency was changed
+func (b BuildModuleDependency) update(in []byte, logger log.Logger) ([]byte, *DependencyUpdateNotification, error) {
+	versionExp, constraint, err := b.versionMatchers()
 	if err != nil {
-		config.exitHandler.Error(fmt.Errorf("unable to read %s\n%w", b.BuildModulePath, err))
-		return
+		return nil, nil, err
+	}
+
+	var patterns dependencyPatterns
+	patterns.cpe, err = compileOptional(b.CPEPattern)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to compile cpe regex %s\n%w", b.CPEPattern, err)
+	}
+
+	patterns.purl, err = compileOptional(b.PURLPattern)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to compile cpe regex %s\n%w", b.PURLPattern, err)
+	}
+
+	patterns.name, err = compileOptional(b.NamePattern)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to compile name regex %s\n%w", b.NamePattern, err)
+	}
+
+	var notification *DependencyUpdateNotification
+	out, err := internal.RenderTOML(in, b.BuildModulePath, b.tomlOptions(), func(md map[string]interface{}) error {
+		dependencies, err := buildModuleDependencies(md, b.MetadataSubkey)
+		if err != nil {
+			return err
+		}
+
+		matched := 0
+		matchedArches := map[string]bool{}
+		for _, dep := range dependencies {
+			if depID, _ := dep["id"].(string); depID != b.ID {
+				continue
+			}
+
+			uri, sha256, found := b.archValue(dependencyArch(dep, b.normalizeArch))
+			if !found {
+				continue
+			}
+
+			depVersion, ok := b.selectsVersion(dep, versionExp, constraint, logger)
+			if !ok {
+				continue
+			}
+
+			matched++
+			if b.MatchURI != "" && matched > 1 {
+				return fmt.Errorf("more than one %s dependency has uri %s", b.ID, b.MatchURI)
+			}
+
+			before := fmt.Sprint(dep)
+
+			if err := b.updateDependency(dep, uri, sha256, depVersion, patterns); err != nil {
+				return err
+			}
+			matchedArches[dependencyArch(dep, b.normalizeArch)] = true
+
+			if notification == nil && fmt.Sprint(dep) != before {
+				notification = &DependencyUpdateNotification{
+					ID:         b.ID,
+					OldVersion: depVersion,
+					NewVersion: b.Version,
+					File:       b.BuildModulePath,
+				}
+			}
+		}
+
+		if matched == 0 && b.RequireMatch && !b.AddIfMissing {
+			return fmt.Errorf("no %s dependency matched arch %s and %s", b.ID, strings.Join(b.updateArches(), ","), b.selection())
+		}
+
+		if !b.AddIfMissing {
+			return nil
+		}
+
+		for _, arch := range b.updateArches() {
+			if matchedArches[arch] {
+				continue
+			}
+
+			dep, err := b.newDependency(dependencies, arch, patterns)
+			if err != nil {
+				return err
+			}
+			dependencies = append(dependencies, dep)
+			logger.Headerf("Added:        %s %s (%s)", b.ID, b.Version, arch)
+
+			if notification == nil {
+				notification = &DependencyUpdateNotification{
+					ID:         b.ID,
+					NewVersion: b.Version,
+					File:       b.BuildModulePath,
+				}
+			}
+		}
+
+		return setBuildModuleDependencies(md, b.MetadataSubkey, dependencies)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return out, notification, nil
+}
+
+// versionMatchers compiles the version pattern and, if set, the version constraint that select dependencies
+func (b BuildModuleDependency) versionMatchers() (*regexp.Regexp, *semver.Constraints, error) {
+	versionExp, err := regexp.Compile(b.versionRegex())
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to compile version regex %s\n%w", b.VersionPattern, err)
+	}
+
+	if b.VersionConstraint == "" {
+		return versionExp, nil, nil
+	}
+
+	constraint, err := semver.NewConstraint(b.VersionConstraint)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to parse version constraint %s\n%w", b.VersionConstraint, err)
+	}
+
+	return versionExp, constraint, nil
+}
+
+// selectsVersion returns the version of dep and true if dep is selected by MatchURI, the version constraint or the
+// version pattern, in that order of precedence
+func (b BuildModuleDependency) selectsVersion(dep map[string]interface{}, versionExp *regexp.Regexp, constraint *semver.Constraints, logger log.Logger) (string, bool) {
+	depVersion, ok := dep["version"].(string)
+	if !ok {
+		return "", false
+	}
+
+	if b.MatchURI != "" {
+		depURI, _ := dep["uri"].(string)
+		return depVersion, depURI == b.MatchURI
 	}
 
-	// save any leading comments, this is to preserve license headers
-	// inline comments will be lost
-	comments := []byte{}
-	for i, line := range bytes.SplitAfter(c, []byte("\n")) {
-		if bytes.HasPrefix(line, []byte("#")) || (i > 0 && len(bytes.TrimSpace(line)) == 0) {
-			comments = append(comments, line...)
+	if constraint != nil {
+		v, err := semver.NewVersion(depVersion)
+		if err != nil {
+			logger.Headerf("Warning: skipping %s %s, its version is not SemVer", b.ID, depVersion)
+			return "", false
+		}
+
+		return depVersion, constraint.Check(v)
+	}
+
+	return depVersion, versionExp.MatchString(depVersion)
+}
+
+// selection describes how dependencies are selected, for error messages
+func (b BuildModuleDependency) selection() string {
+	if b.MatchURI != "" {
+		return fmt.Sprintf("uri %s", b.MatchURI)
+	} else if b.VersionConstraint != "" {
+		return fmt.Sprintf("version constraint %s", b.VersionConstraint)
+	}
+
+	return fmt.Sprintf("version pattern %s", b.VersionPattern)
+}
+
+// tomlOptions returns the options the build module is rendered and written with
+func (b BuildModuleDependency) tomlOptions() internal.TOMLOptions {
+	return internal.TOMLOptions{Indent: b.TOMLIndent, Backup: b.Backup, FailOnNoChange: b.FailOnNoChange}
+}
+
+// dependencyPatterns are the compiled patterns of the version in the purl, cpes and name of a dependency, a nil
+// pattern matches the current version of the dependency
+type dependencyPatterns struct {
+	purl *regexp.Regexp
+	cpe  *regexp.Regexp
+	name *regexp.Regexp
+}
+
+// updateDependency applies the new version, uri, checksums, purl, cpes, name and EOL date to dep, whose current version is
+// depVersion
+func (b BuildModuleDependency) updateDependency(dep map[string]interface{}, uri string, sha256 string, depVersion string, patterns dependencyPatterns) error {
+	dep["version"] = b.Version
+	if b.BuildNumber != "" {
+		if _, found := dep["revision"]; found {
+			dep["revision"] = b.BuildNumber
 		} else {
-			break // stop on first comment
+			dep["build"] = b.BuildNumber
+		}
+	}
+	dep["uri"] = uri
+	updateChecksum(dep, b.Algorithm, sha256)
+	if b.SourceSHA256 != "" {
+		updateSourceChecksum(dep, b.Algorithm, b.SourceSHA256)
+	}
+	if b.Source != "" {
+		dep["source"] = b.Source
+	}
+	if len(b.Stacks) > 0 {
+		stacks := make([]interface{}, len(b.Stacks))
+		for i, stack := range b.Stacks {
+			stacks[i] = stack
 		}
+		dep["stacks"] = stacks
 	}
 
-	md := make(map[string]interface{})
-	if err := toml.Unmarshal(c, &md); err != nil {
-		config.exitHandler.Error(fmt.Errorf("unable to decode md%s\n%w", b.BuildModulePath, err))
-		return
+	// without a new purl version there is nothing to substitute, replacing the version with nothing breaks the purl
+	purlUnwrapped, found := dep["purl"]
+	if found && b.PURL != "" {
+		purl, ok := purlUnwrapped.(string)
+		if ok {
+			updated := versionOrPattern(patterns.purl, depVersion).ReplaceAllString(purl, b.PURL)
+			if isValidPURL(purl) && !isValidPURL(updated) {
+				return fmt.Errorf("unable to update purl %s, the result %s is not a valid purl", purl, updated)
+			}
+			dep["purl"] = updated
+		}
 	}
 
+	cpesUnwrapped, found := dep["cpes"]
+	if found {
+		cpes, ok := cpesUnwrapped.([]interface{})
+		if ok {
+			for i := 0; i < len(cpes); i++ {
+				cpe, ok := cpes[i].(string)
+				if !ok {
+					continue
+				}
+
+				cpes[i] = versionOrPattern(patterns.cpe, depVersion).ReplaceAllString(cpe, b.CPE)
+			}
+		}
+	}
+
+	if b.Name != "" {
+		if name, ok := dep["name"].(string); ok {
+			dep["name"] = versionOrPattern(patterns.name, depVersion).ReplaceAllString(name, b.Name)
+		}
+	}
+
+	if b.EolID != "" {
+		eolDate, err := internal.GetEolDateWithCache(b.EolID, b.Version, b.EolCache)
+		if err != nil {
+			return fmt.Errorf("unable to fetch deprecation_date\n%w", err)
+		}
+
+		if eolDate != "" {
+			updateEOLDate(dep, eolDate)
+		}
+	}
+
+	return nil
+}
+
+// newDependency returns a dependency for arch to add when no existing dependency matched. It is a copy of the first
+// dependency with the same id, so that fields like the name, stacks and licenses are kept, with its arch changed.
+// When there is no such dependency, only the supplied fields are set.
+func (b BuildModuleDependency) newDependency(dependencies []map[string]interface{}, arch string, patterns dependencyPatterns) (map[string]interface{}, error) {
+	uri, sha256, _ := b.archValue(arch)
+
+	for _, template := range dependencies {
+		if depID, _ := template["id"].(string); depID != b.ID {
+			continue
+		}
+
+		dep := maps.Clone(template)
+		if cpes, ok := dep["cpes"].([]interface{}); ok {
+			dep["cpes"] = slices.Clone(cpes)
+		}
+		if purl, ok := dep["purl"].(string); ok {
+			dep["purl"] = withPURLArch(purl, arch)
+		}
+
+		depVersion, _ := dep["version"].(string)
+		if err := b.updateDependency(dep, uri, sha256, depVersion, patterns); err != nil {
+			return nil, err
+		}
+
+		return dep, nil
+	}
+
+	dep := map[string]interface{}{"id": b.ID}
+	if err := b.updateDependency(dep, uri, sha256, "", patterns); err != nil {
+		return nil, err
+	}
+
+	// PURL and CPE are usually just versions, they are only used when they are complete
+	if strings.HasPrefix(b.PURL, "pkg:") {
+		dep["purl"] = withPURLArch(b.PURL, arch)
+	}
+	if strings.HasPrefix(b.CPE, "cpe:") {
+		dep["cpes"] = []interface{}{b.CPE}
+	}
+
+	return dep, nil
+}
+
+var purlArchPattern = regexp.MustCompile(`arch=[^&#]*`)
+
+// withPURLArch sets the arch qualifier of purl
+func withPURLArch(purl string, arch string) string {
+	if purlArchPattern.MatchString(purl) {
+		return purlArchPattern.ReplaceAllString(purl, "arch="+arch)
+	}
+
+	if strings.Contains(purl, "?") {
+		return purl + "&arch=" + arch
+	}
+
+	return purl + "?arch=" + arch
+}
+
+// updateArches returns the normalized arches that are updated
+func (b BuildModuleDependency) updateArches() []string {
+	if len(b.ArchValues) > 0 {
+		var arches []string
+		for _, arch := range sortedKeys(b.ArchValues) {
+			arches = append(arches, b.normalizeArch(arch))
+		}
+		return arches
+	}
+
+	return []string{b.normalizeArch(b.Arch)}
+}
+
+// compileOptional compiles pattern, returning nil if it is empty
+func compileOptional(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+
+	return regexp.Compile(pattern)
+}
+
+// versionOrPattern returns exp, or a pattern matching the current version of the dependency when exp is nil
+func versionOrPattern(exp *regexp.Regexp, version string) *regexp.Regexp {
+	if exp != nil {
+		return exp
+	}
+
+	return regexp.MustCompile(regexp.QuoteMeta(version))
+}
+
+// logSummary logs the details of the update
+func (b BuildModuleDependency) logSummary(logger log.Logger) {
+	_, _ = fmt.Fprintf(logger.TitleWriter(), "\n%s\n", log.FormatIdentity(b.ID, cmp.Or(b.VersionConstraint, b.VersionPattern)))
+	logger.Headerf("Arch:         %s", b.Arch)
+	for _, arch := range sortedKeys(b.ArchValues) {
+		logger.Headerf("  %-11s %s (%s)", arch+":", b.ArchValues[arch].URI, b.ArchValues[arch].SHA256)
+	}
+	logger.Headerf("Version:      %s", b.Version)
+	logger.Headerf("Build:        %s", b.BuildNumber)
+	logger.Headerf("PURL:         %s", b.PURL)
+	logger.Headerf("CPEs:         %s", b.CPE)
+	logger.Headerf("Name:         %s", b.Name)
+	logger.Headerf("URI:          %s", b.URI)
+	logger.Headerf("SHA256:       %s", b.SHA256)
+	logger.Headerf("Source:       %s", b.Source)
+	logger.Headerf("SourceSHA256: %s", b.SourceSHA256)
+	logger.Headerf("Algorithm:    %s", b.Algorithm)
+	logger.Headerf("Stacks:       %s", strings.Join(b.Stacks, ", "))
+	logger.Headerf("EOL ID:       %s", b.EolID)
+}
+
+// uris returns the new dependency and source URIs
+func (b BuildModuleDependency) uris() []string {
+	var uris []string
+	if len(b.ArchValues) > 0 {
+		for _, arch := range sortedKeys(b.ArchValues) {
+			uris = append(uris, b.ArchValues[arch].URI)
+		}
+	} else if b.URI != "" {
+		uris = append(uris, b.URI)
+	}
+
+	if b.Source != "" {
+		uris = append(uris, b.Source)
+	}
+
+	return uris
+}
+
+// buildModuleDependencies returns the `[[metadata.dependencies]]` of a decoded build module, or the
+// `[[metadata.dependencies.<subkey>]]` if subkey is set
+func buildModuleDependencies(md map[string]interface{}, subkey string) ([]map[string]interface{}, error) {
 	metadataUnwrapped, found := md["metadata"]
 	if !found {
-		config.exitHandler.Error(fmt.Errorf("unable to find metadata block"))
-		return
+		return nil, fmt.Errorf("unable to find metadata block")
 	}
 
 	metadata, ok := metadataUnwrapped.(map[string]interface{})
 	if !ok {
-		config.exitHandler.Error(fmt.Errorf("unable to cast metadata"))
-		return
+		return nil, fmt.Errorf("unable to cast metadata")
 	}
 
 	dependenciesUnwrapped, found := metadata["dependencies"]
 	if !found {
-		config.exitHandler.Error(fmt.Errorf("unable to find dependencies block"))
-		return
+		return nil, fmt.Errorf("unable to find dependencies block")
 	}
 
-	dependencies, ok := dependenciesUnwrapped.([]map[string]interface{})
-	if !ok {
-		config.exitHandler.Error(fmt.Errorf("unable to cast dependencies"))
-		return
-	}
+	if subkey != "" {
+		groups, ok := dependenciesUnwrapped.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unable to cast dependencies to a table of %s", subkey)
+		}
 
-	for _, dep := range dependencies {
-		depIDUnwrapped, found := dep["id"]
+		dependenciesUnwrapped, found = groups[subkey]
 		if !found {
-			continue
+			return nil, fmt.Errorf("unable to find dependencies.%s block", subkey)
 		}
-		depID, ok := depIDUnwrapped.(string)
+	}
+
+	dependencies, ok := internal.TableArray(dependenciesUnwrapped)
+	if !ok {
+		return nil, fmt.Errorf("unable to cast dependencies")
+	}
+
+	return dependencies, nil
+}
+
+// setBuildModuleDependencies replaces the dependencies returned by buildModuleDependencies
+func setBuildModuleDependencies(md map[string]interface{}, subkey string, dependencies []map[string]interface{}) error {
+	metadata, ok := md["metadata"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("unable to cast metadata")
+	}
+
+	if subkey != "" {
+		groups, ok := metadata["dependencies"].(map[string]interface{})
 		if !ok {
-			continue
+			return fmt.Errorf("unable to cast dependencies to a table of %s", subkey)
 		}
 
-		// extract the arch from the PURL, it's the only place it lives consistently at the moment
-		var depArch string
-		purlUnwrapped, found := dep["purl"]
-		if found {
-			purl, ok := purlUnwrapped.(string)
-			if ok {
-				purlArchExp := regexp.MustCompile(`arch=(.*)`)
-				purlArchMatches := purlArchExp.FindStringSubmatch(purl)
-				if len(purlArchMatches) == 2 {
-					depArch = purlArchMatches[1]
-				}
+		groups[subkey] = dependencies
+		return nil
+	}
+
+	metadata["dependencies"] = dependencies
+	return nil
+}
+
+// versionRegex returns the regular expression used to match dependency versions
+func (b BuildModuleDependency) versionRegex() string {
+	if b.ExactVersion {
+		return ExactVersionPattern(b.VersionPattern)
+	}
+
+	return b.VersionPattern
+}
+
+// ExactVersionPattern returns a regular expression that only matches version exactly
+func ExactVersionPattern(version string) string {
+	return fmt.Sprintf("^%s$", regexp.QuoteMeta(version))
+}
+
+// IsAnchoredPattern returns true if pattern is anchored at both the start and end, so it cannot match a longer version
+func IsAnchoredPattern(pattern string) bool {
+	return strings.HasPrefix(pattern, "^") && strings.HasSuffix(pattern, "$")
+}
+
+// archValue returns the uri and sha256 to use for a dependency of the given arch, or false if that arch is not updated
+func (b BuildModuleDependency) archValue(arch string) (string, string, bool) {
+	if len(b.ArchValues) > 0 {
+		for a, v := range b.ArchValues {
+			if b.normalizeArch(a) == arch {
+				return v.URI, v.SHA256, true
 			}
 		}
+		return "", "", false
+	}
+
+	return b.URI, b.SHA256, b.normalizeArch(b.Arch) == arch
+}
 
-		// if not set, we presently need to default to amd64 because a lot of deps do not specify arch
-		//   in the future when we add the arch field to our deps, then we can remove this because empty should then mean noarch
-		if depArch == "" {
-			depArch = "amd64"
+// defaultArchAliases are common alternate spellings of the CNB arch names
+var defaultArchAliases = map[string]string{
+	"x86_64":  "amd64",
+	"x86-64":  "amd64",
+	"x64":     "amd64",
+	"aarch64": "arm64",
+	"arm64v8": "arm64",
+}
+
+// normalizeArch maps an arch to its CNB name using ArchAliases and, if NormalizeArch is set, the default aliases
+func (b BuildModuleDependency) normalizeArch(arch string) string {
+	if a, found := b.ArchAliases[arch]; found {
+		return a
+	}
+
+	if b.NormalizeArch {
+		if a, found := defaultArchAliases[strings.ToLower(arch)]; found {
+			return a
 		}
+	}
 
-		if depID == b.ID && depArch == b.Arch {
-			depVersionUnwrapped, found := dep["version"]
-			if !found {
-				continue
-			}
+	return arch
+}
 
-			depVersion, ok := depVersionUnwrapped.(string)
-			if !ok {
-				continue
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isValidPURL returns true if purl parses as a purl with a type, name and version
+func isValidPURL(purl string) bool {
+	p, err := packageurl.FromString(purl)
+	return err == nil && p.Type != "" && p.Name != "" && p.Version != ""
+}
+
+// dependencyArch extracts the arch from the PURL, it's the only place it lives consistently at the moment
+func dependencyArch(dep map[string]interface{}, normalize func(string) string) string {
+	var depArch string
+	purlUnwrapped, found := dep["purl"]
+	if found {
+		purl, ok := purlUnwrapped.(string)
+		if ok {
+			purlArchExp := regexp.MustCompile(`arch=(.*)`)
+			purlArchMatches := purlArchExp.FindStringSubmatch(purl)
+			if len(purlArchMatches) == 2 {
+				depArch = purlArchMatches[1]
 			}
+		}
+	}
 
-			if versionExp.MatchString(depVersion) {
-				dep["version"] = b.Version
-				dep["uri"] = b.URI
-				dep["sha256"] = b.SHA256
-				if b.SourceSHA256 != "" {
-					dep["source-sha256"] = b.SourceSHA256
-				}
-				if b.Source != "" {
-					dep["source"] = b.Source
-				}
+	// if not set, we presently need to default to amd64 because a lot of deps do not specify arch
+	//   in the future when we add the arch field to our deps, then we can remove this because empty should then mean noarch
+	if depArch == "" {
+		depArch = "amd64"
+	}
 
-				purlUnwrapped, found := dep["purl"]
-				if found {
-					purl, ok := purlUnwrapped.(string)
-					if ok {
-						dep["purl"] = purlExp.ReplaceAllString(purl, b.PURL)
-					}
-				}
+	return normalize(depArch)
+}
 
-				cpesUnwrapped, found := dep["cpes"]
-				if found {
-					cpes, ok := cpesUnwrapped.([]interface{})
-					if ok {
-						for i := 0; i < len(cpes); i++ {
-							cpe, ok := cpes[i].(string)
-							if !ok {
-								continue
-							}
-
-							cpes[i] = cpeExp.ReplaceAllString(cpe, b.CPE)
-						}
-					}
-				}
+// updateEOLDate sets the end of life date of a dependency, using `eol-date` if the dependency already has it or
+// `deprecation_date` otherwise
+func updateEOLDate(dep map[string]interface{}, date string) {
+	if _, found := dep["eol-date"]; found {
+		dep["eol-date"] = date
+		return
+	}
 
-				if b.EolID != "" {
-					eolDate, err := internal.GetEolDate(b.EolID, b.Version)
-					if err != nil {
-						config.exitHandler.Error(fmt.Errorf("unable to fetch deprecation_date"))
-						return
-					}
+	dep["deprecation_date"] = date
+}
 
-					if eolDate != "" {
-						dep["deprecation_date"] = eolDate
-					}
+// updateChecksum sets the artifact digest of a dependency, using `checksum` or the legacy `sha256` key
+func updateChecksum(dep map[string]interface{}, algorithm string, digest string) {
+	setChecksum(dep, "checksum", "sha256", algorithm, digest)
+}
+
+// updateSourceChecksum sets the source digest of a dependency, using `source-checksum` or the legacy `source-sha256` key
+func updateSourceChecksum(dep map[string]interface{}, algorithm string, digest string) {
+	setChecksum(dep, "source-checksum", "source-sha256", algorithm, digest)
+}
+
+// setChecksum writes `<algorithm>:<digest>` to key if the dependency already uses it, detecting the existing
+// algorithm when none is given. Otherwise the digest goes to legacyKey, unless an algorithm other than sha256 is
+// requested, which the legacy key cannot express.
+func setChecksum(dep map[string]interface{}, key string, legacyKey string, algorithm string, digest string) {
+	if existing, found := dep[key]; found {
+		if algorithm == "" {
+			algorithm = DefaultChecksumAlgorithm
+			if checksum, ok := existing.(string); ok {
+				if prefix, _, found := strings.Cut(checksum, ":"); found && prefix != "" {
+					algorithm = prefix
 				}
 			}
 		}
-	}
 
-	c, err = utils.Marshal(md)
-	if err != nil {
-		config.exitHandler.Error(fmt.Errorf("unable to encode md %s\n%w", b.BuildModulePath, err))
+		dep[key] = fmt.Sprintf("%s:%s", algorithm, digest)
 		return
 	}
 
-	c = append(comments, c...)
-
-	// #nosec G306 - permissions need to be 644 on the build module
-	if err := os.WriteFile(b.BuildModulePath, c, 0644); err != nil {
-		config.exitHandler.Error(fmt.Errorf("unable to write %s\n%w", b.BuildModulePath, err))
+	if algorithm != "" && algorithm != DefaultChecksumAlgorithm {
+		delete(dep, legacyKey)
+		dep[key] = fmt.Sprintf("%s:%s", algorithm, digest)
 		return
 	}
+
+	dep[legacyKey] = digest
 }
@@ -17,10 +17,17 @@
 package carton_test
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/buildpacks/libcnb/v2/mocks"
+	"github.com/jarcoal/httpmock"
 	. "github.com/onsi/gomega"
 	"github.com/sclevine/spec"
 	"github.com/stretchr/testify/mock"
@@ -28,6 +35,7 @@ import (
 	libpakTesting "github.com/paketo-buildpacks/libpak/v2/testing"
 
 	"github.com/paketo-buildpacks/libpak-tools/carton"
+	"github.com/paketo-buildpacks/libpak-tools/internal"
 )
 
 func testBuildpackDependency(t *testing.T, context spec.G, it spec.S) {
@@ -293,6 +301,179 @@ source-sha256 = "test-source-sha256-2"
 `))
 	})
 
+	it("updates all arches of a dependency at once", func() {
+		Expect(os.WriteFile(path, []byte(`api = "0.7"
+[buildpack]
+id = "some-buildpack"
+name = "Some Buildpack"
+version = "1.2.3"
+
+[[metadata.dependencies]]
+id      = "test-id"
+name    = "Test Name"
+version = "test-version-1"
+uri     = "test-uri-amd64-1"
+sha256  = "test-sha256-amd64-1"
+stacks  = [ "test-stack" ]
+purl    = "pkg:generic/test-jre@test-version-1?arch=amd64"
+
+[[metadata.dependencies]]
+id      = "test-id"
+name    = "Test Name"
+version = "test-version-1"
+uri     = "test-uri-arm64-1"
+sha256  = "test-sha256-arm64-1"
+stacks  = [ "test-stack" ]
+purl    = "pkg:generic/test-jre@test-version-1?arch=arm64"
+`), 0600)).To(Succeed())
+
+		d := carton.BuildModuleDependency{
+			BuildModulePath: path,
+			ID:              "test-id",
+			ArchValues: map[string]carton.ArchValue{
+				"amd64": {URI: "test-uri-amd64-2", SHA256: "test-sha256-amd64-2"},
+				"arm64": {URI: "test-uri-arm64-2", SHA256: "test-sha256-arm64-2"},
+			},
+			Version:        "test-version-2",
+			VersionPattern: `test-version-[\d]`,
+			PURL:           "test-version-2",
+			PURLPattern:    `test-version-[\d]`,
+		}
+
+		d.Update(carton.WithExitHandler(exitHandler))
+
+		Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`api = "0.7"
+[buildpack]
+id = "some-buildpack"
+name = "Some Buildpack"
+version = "1.2.3"
+
+[[metadata.dependencies]]
+id      = "test-id"
+name    = "Test Name"
+version = "test-version-2"
+uri     = "test-uri-amd64-2"
+sha256  = "test-sha256-amd64-2"
+stacks  = [ "test-stack" ]
+purl    = "pkg:generic/test-jre@test-version-2?arch=amd64"
+
+[[metadata.dependencies]]
+id      = "test-id"
+name    = "Test Name"
+version = "test-version-2"
+uri     = "test-uri-arm64-2"
+sha256  = "test-sha256-arm64-2"
+stacks  = [ "test-stack" ]
+purl    = "pkg:generic/test-jre@test-version-2?arch=arm64"
+`))
+	})
+
+	context("arch aliases", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(path, []byte(`api = "0.7"
+[buildpack]
+id = "some-buildpack"
+name = "Some Buildpack"
+version = "1.2.3"
+
+[[metadata.dependencies]]
+id      = "test-id"
+name    = "Test Name"
+version = "test-version-1"
+uri     = "test-uri-amd64-1"
+sha256  = "test-sha256-amd64-1"
+stacks  = [ "test-stack" ]
+purl    = "pkg:generic/test-jre@test-version-1?arch=amd64"
+
+[[metadata.dependencies]]
+id      = "test-id"
+name    = "Test Name"
+version = "test-version-1"
+uri     = "test-uri-arm64-1"
+sha256  = "test-sha256-arm64-1"
+stacks  = [ "test-stack" ]
+purl    = "pkg:generic/test-jre@test-version-1?arch=aarch64"
+`), 0600)).To(Succeed())
+		})
+
+		it("maps x86_64 to amd64", func() {
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "x86_64",
+				NormalizeArch:   true,
+				SHA256:          "test-sha256-amd64-2",
+				URI:             "test-uri-amd64-2",
+				Version:         "test-version-2",
+				VersionPattern:  `test-version-[\d]`,
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler))
+
+			body, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(ContainSubstring(`uri = "test-uri-amd64-2"`))
+			Expect(string(body)).To(ContainSubstring(`uri = "test-uri-arm64-1"`))
+		})
+
+		it("maps aarch64 entries to arm64", func() {
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "arm64",
+				NormalizeArch:   true,
+				SHA256:          "test-sha256-arm64-2",
+				URI:             "test-uri-arm64-2",
+				Version:         "test-version-2",
+				VersionPattern:  `test-version-[\d]`,
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler))
+
+			body, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(ContainSubstring(`uri = "test-uri-amd64-1"`))
+			Expect(string(body)).To(ContainSubstring(`uri = "test-uri-arm64-2"`))
+		})
+
+		it("does not map arches unless asked", func() {
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "x86_64",
+				SHA256:          "test-sha256-amd64-2",
+				URI:             "test-uri-amd64-2",
+				Version:         "test-version-2",
+				VersionPattern:  `test-version-[\d]`,
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler))
+
+			body, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(ContainSubstring(`uri = "test-uri-amd64-1"`))
+		})
+
+		it("uses custom arch aliases", func() {
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "arm",
+				ArchAliases:     map[string]string{"arm": "arm64", "aarch64": "arm64"},
+				SHA256:          "test-sha256-arm64-2",
+				URI:             "test-uri-arm64-2",
+				Version:         "test-version-2",
+				VersionPattern:  `test-version-[\d]`,
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler))
+
+			body, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(ContainSubstring(`uri = "test-uri-arm64-2"`))
+		})
+	})
+
 	it("updates dependency with missing purl, still updates cpe", func() {
 		Expect(os.WriteFile(path, []byte(`api = "0.7"
 [buildpack]
@@ -447,24 +628,21 @@ cpes    = 1234
 `))
 	})
 
-	it("updates indented dependency", func() {
-		Expect(os.WriteFile(path, []byte(`# it should preserve
-#   these comments
-#      exactly
-
-api = "0.6"
+	it("does not update a purl that is no longer valid after replacement", func() {
+		Expect(os.WriteFile(path, []byte(`api = "0.7"
 [buildpack]
 id = "some-buildpack"
 name = "Some Buildpack"
 version = "1.2.3"
 
 [[metadata.dependencies]]
-  id      = "test-id"
-  name    = "Test Name"
-  version = "test-version-1"
-  uri     = "test-uri-1"
-  sha256  = "test-sha256-1"
-  stacks  = [ "test-stack" ]
+id      = "test-id"
+name    = "Test Name"
+version = "test-version-1"
+uri     = "test-uri-1"
+sha256  = "test-sha256-1"
+stacks  = [ "test-stack" ]
+purl    = "pkg:generic/test-jre@different-version-1?arch=amd64"
 `), 0600)).To(Succeed())
 
 		d := carton.BuildModuleDependency{
@@ -475,30 +653,1267 @@ version = "1.2.3"
 			URI:             "test-uri-2",
 			Version:         "test-version-2",
 			VersionPattern:  `test-version-[\d]`,
+			PURL:            "different-version-2",
+			PURLPattern:     `.*`,
 		}
 
 		d.Update(carton.WithExitHandler(exitHandler))
 
-		body, err := os.ReadFile(path)
-		Expect(err).NotTo(HaveOccurred())
-		Expect(string(body)).To(HavePrefix(`# it should preserve
-#   these comments
-#      exactly
+		exitHandler.AssertCalled(t, "Error", mock.MatchedBy(func(err error) bool {
+			return strings.Contains(err.Error(), "is not a valid purl")
+		}))
+		Expect(os.ReadFile(path)).To(ContainSubstring(`purl    = "pkg:generic/test-jre@different-version-1?arch=amd64"`))
+	})
 
-api = "0.6"`))
-		Expect(body).To(libpakTesting.MatchTOML(`api = "0.6"
+	it("updates dependency with sha512 checksums", func() {
+		Expect(os.WriteFile(path, []byte(`api = "0.7"
 [buildpack]
 id = "some-buildpack"
 name = "Some Buildpack"
 version = "1.2.3"
 
 [[metadata.dependencies]]
-  id      = "test-id"
-  name    = "Test Name"
-  version = "test-version-2"
-  uri     = "test-uri-2"
-  sha256  = "test-sha256-2"
-  stacks  = [ "test-stack" ]
+id              = "test-id"
+name            = "Test Name"
+version         = "test-version-1"
+uri             = "test-uri-1"
+checksum        = "sha512:test-sha512-1"
+stacks          = [ "test-stack" ]
+source          = "test-source-uri-1"
+source-checksum = "sha512:test-source-sha512-1"
+`), 0600)).To(Succeed())
+
+		d := carton.BuildModuleDependency{
+			BuildModulePath: path,
+			ID:              "test-id",
+			Arch:            "amd64",
+			SHA256:          "test-sha512-2",
+			URI:             "test-uri-2",
+			Version:         "test-version-2",
+			VersionPattern:  `test-version-[\d]`,
+			Source:          "test-source-uri-2",
+			SourceSHA256:    "test-source-sha512-2",
+		}
+
+		d.Update(carton.WithExitHandler(exitHandler))
+
+		Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`api = "0.7"
+[buildpack]
+id = "some-buildpack"
+name = "Some Buildpack"
+version = "1.2.3"
+
+[[metadata.dependencies]]
+id              = "test-id"
+name            = "Test Name"
+version         = "test-version-2"
+uri             = "test-uri-2"
+checksum        = "sha512:test-sha512-2"
+stacks          = [ "test-stack" ]
+source          = "test-source-uri-2"
+source-checksum = "sha512:test-source-sha512-2"
+`))
+	})
+
+	it("updates dependency with an explicit checksum algorithm", func() {
+		Expect(os.WriteFile(path, []byte(`api = "0.7"
+[buildpack]
+id = "some-buildpack"
+name = "Some Buildpack"
+version = "1.2.3"
+
+[[metadata.dependencies]]
+id      = "test-id"
+name    = "Test Name"
+version = "test-version-1"
+uri     = "test-uri-1"
+sha256  = "test-sha256-1"
+stacks  = [ "test-stack" ]
+`), 0600)).To(Succeed())
+
+		d := carton.BuildModuleDependency{
+			BuildModulePath: path,
+			ID:              "test-id",
+			Arch:            "amd64",
+			SHA256:          "test-sha1-2",
+			URI:             "test-uri-2",
+			Version:         "test-version-2",
+			VersionPattern:  `test-version-[\d]`,
+			Algorithm:       "sha1",
+		}
+
+		d.Update(carton.WithExitHandler(exitHandler))
+
+		Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`api = "0.7"
+[buildpack]
+id = "some-buildpack"
+name = "Some Buildpack"
+version = "1.2.3"
+
+[[metadata.dependencies]]
+id       = "test-id"
+name     = "Test Name"
+version  = "test-version-2"
+uri      = "test-uri-2"
+checksum = "sha1:test-sha1-2"
+stacks   = [ "test-stack" ]
+`))
+	})
+
+	it("replaces the algorithm of an existing checksum when sha256 is explicit", func() {
+		Expect(os.WriteFile(path, []byte(`api = "0.7"
+[buildpack]
+id = "some-buildpack"
+
+[[metadata.dependencies]]
+id       = "test-id"
+version  = "test-version-1"
+uri      = "test-uri-1"
+checksum = "sha512:test-sha512-1"
+`), 0600)).To(Succeed())
+
+		d := carton.BuildModuleDependency{
+			BuildModulePath: path,
+			ID:              "test-id",
+			Arch:            "amd64",
+			SHA256:          "test-sha256-2",
+			URI:             "test-uri-2",
+			Version:         "test-version-2",
+			VersionPattern:  `test-version-[\d]`,
+			Algorithm:       carton.DefaultChecksumAlgorithm,
+		}
+
+		d.Update(carton.WithExitHandler(exitHandler), carton.WithProgressWriter(&bytes.Buffer{}))
+
+		Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`api = "0.7"
+[buildpack]
+id = "some-buildpack"
+
+[[metadata.dependencies]]
+id       = "test-id"
+version  = "test-version-2"
+uri      = "test-uri-2"
+checksum = "sha256:test-sha256-2"
+`))
+	})
+
+	it("updates dependency with a separate build number", func() {
+		Expect(os.WriteFile(path, []byte(`api = "0.7"
+[buildpack]
+id = "some-buildpack"
+name = "Some Buildpack"
+version = "1.2.3"
+
+[[metadata.dependencies]]
+id      = "test-id"
+name    = "Test Name"
+version = "test-version-1"
+build   = "7"
+uri     = "test-uri-1"
+sha256  = "test-sha256-1"
+stacks  = [ "test-stack" ]
+
+[[metadata.dependencies]]
+id       = "test-id"
+name     = "Test Name"
+version  = "test-version-1"
+revision = "7"
+uri      = "test-uri-1"
+sha256   = "test-sha256-1"
+stacks   = [ "test-stack" ]
+`), 0600)).To(Succeed())
+
+		d := carton.BuildModuleDependency{
+			BuildModulePath: path,
+			ID:              "test-id",
+			Arch:            "amd64",
+			SHA256:          "test-sha256-2",
+			URI:             "test-uri-2",
+			Version:         "test-version-2",
+			VersionPattern:  `test-version-[\d]`,
+			BuildNumber:     "9",
+		}
+
+		d.Update(carton.WithExitHandler(exitHandler))
+
+		Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`api = "0.7"
+[buildpack]
+id = "some-buildpack"
+name = "Some Buildpack"
+version = "1.2.3"
+
+[[metadata.dependencies]]
+id      = "test-id"
+name    = "Test Name"
+version = "test-version-2"
+build   = "9"
+uri     = "test-uri-2"
+sha256  = "test-sha256-2"
+stacks  = [ "test-stack" ]
+
+[[metadata.dependencies]]
+id       = "test-id"
+name     = "Test Name"
+version  = "test-version-2"
+revision = "9"
+uri      = "test-uri-2"
+sha256   = "test-sha256-2"
+stacks   = [ "test-stack" ]
 `))
 	})
+
+	context("exact version", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(path, []byte(`api = "0.7"
+[buildpack]
+id = "some-buildpack"
+name = "Some Buildpack"
+version = "1.2.3"
+
+[[metadata.dependencies]]
+id      = "test-id"
+name    = "Test Name"
+version = "1.20"
+uri     = "test-uri-1.20"
+sha256  = "test-sha256-1.20"
+stacks  = [ "test-stack" ]
+
+[[metadata.dependencies]]
+id      = "test-id"
+name    = "Test Name"
+version = "1.2"
+uri     = "test-uri-1.2"
+sha256  = "test-sha256-1.2"
+stacks  = [ "test-stack" ]
+`), 0600)).To(Succeed())
+		})
+
+		it("does not match a longer version", func() {
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "amd64",
+				SHA256:          "test-sha256-1.3",
+				URI:             "test-uri-1.3",
+				Version:         "1.3",
+				VersionPattern:  "1.2",
+				ExactVersion:    true,
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler))
+
+			Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`api = "0.7"
+[buildpack]
+id = "some-buildpack"
+name = "Some Buildpack"
+version = "1.2.3"
+
+[[metadata.dependencies]]
+id      = "test-id"
+name    = "Test Name"
+version = "1.20"
+uri     = "test-uri-1.20"
+sha256  = "test-sha256-1.20"
+stacks  = [ "test-stack" ]
+
+[[metadata.dependencies]]
+id      = "test-id"
+name    = "Test Name"
+version = "1.3"
+uri     = "test-uri-1.3"
+sha256  = "test-sha256-1.3"
+stacks  = [ "test-stack" ]
+`))
+		})
+
+		it("escapes regular expression characters", func() {
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "amd64",
+				SHA256:          "test-sha256-1.3",
+				URI:             "test-uri-1.3",
+				Version:         "1.3",
+				VersionPattern:  "1.2.",
+				ExactVersion:    true,
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler))
+
+			body, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).NotTo(ContainSubstring("1.3"))
+		})
+
+		it("detects unanchored patterns", func() {
+			Expect(carton.IsAnchoredPattern(`^1\.2$`)).To(BeTrue())
+			Expect(carton.IsAnchoredPattern(`1\.2`)).To(BeFalse())
+			Expect(carton.IsAnchoredPattern(`^1\.2`)).To(BeFalse())
+			Expect(carton.ExactVersionPattern("1.2")).To(Equal(`^1\.2$`))
+		})
+	})
+
+	context("notify webhook", func() {
+		var (
+			notifications []carton.DependencyUpdateNotification
+			server        *httptest.Server
+		)
+
+		it.Before(func() {
+			notifications = nil
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := carton.DependencyUpdateNotification{}
+				Expect(json.NewDecoder(r.Body).Decode(&n)).To(Succeed())
+				notifications = append(notifications, n)
+			}))
+
+			Expect(os.WriteFile(path, []byte(`api = "0.7"
+[buildpack]
+id = "some-buildpack"
+name = "Some Buildpack"
+version = "1.2.3"
+
+[[metadata.dependencies]]
+id      = "test-id"
+name    = "Test Name"
+version = "test-version-1"
+uri     = "test-uri-1"
+sha256  = "test-sha256-1"
+stacks  = [ "test-stack" ]
+`), 0600)).To(Succeed())
+		})
+
+		it.After(func() {
+			server.Close()
+		})
+
+		it("notifies when the dependency changes", func() {
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "amd64",
+				SHA256:          "test-sha256-2",
+				URI:             "test-uri-2",
+				Version:         "test-version-2",
+				VersionPattern:  `test-version-[\d]`,
+				NotifyWebhook:   server.URL,
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler))
+
+			Expect(notifications).To(Equal([]carton.DependencyUpdateNotification{
+				{ID: "test-id", OldVersion: "test-version-1", NewVersion: "test-version-2", File: path},
+			}))
+		})
+
+		it("does not notify when nothing changes", func() {
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "amd64",
+				SHA256:          "test-sha256-1",
+				URI:             "test-uri-1",
+				Version:         "test-version-1",
+				VersionPattern:  `test-version-[\d]`,
+				NotifyWebhook:   server.URL,
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler))
+
+			Expect(notifications).To(BeEmpty())
+		})
+
+		it("does not fail when the webhook fails", func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			})
+
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "amd64",
+				SHA256:          "test-sha256-2",
+				URI:             "test-uri-2",
+				Version:         "test-version-2",
+				VersionPattern:  `test-version-[\d]`,
+				NotifyWebhook:   server.URL,
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler))
+
+			exitHandler.AssertNotCalled(t, "Error", mock.Anything)
+			body, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(ContainSubstring("test-version-2"))
+		})
+	})
+
+	it("does not update when the uri is not reachable", func() {
+		server := httptest.NewServer(http.NotFoundHandler())
+		defer server.Close()
+
+		Expect(os.WriteFile(path, []byte(`api = "0.7"
+[[metadata.dependencies]]
+id      = "test-id"
+version = "test-version-1"
+uri     = "test-uri-1"
+sha256  = "test-sha256-1"
+`), 0600)).To(Succeed())
+
+		d := carton.BuildModuleDependency{
+			BuildModulePath: path,
+			ID:              "test-id",
+			Arch:            "amd64",
+			SHA256:          "test-sha256-2",
+			URI:             server.URL + "/test-uri-2",
+			Version:         "test-version-2",
+			VersionPattern:  `test-version-[\d]`,
+			CheckURI:        true,
+		}
+
+		d.Update(carton.WithExitHandler(exitHandler))
+
+		exitHandler.AssertCalled(t, "Error", mock.MatchedBy(func(err error) bool {
+			return strings.Contains(err.Error(), "status: 404")
+		}))
+		Expect(os.ReadFile(path)).NotTo(ContainSubstring("test-version-2"))
+	})
+
+	context("quiet", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(path, []byte(`api = "0.7"
+[[metadata.dependencies]]
+id      = "test-id"
+version = "test-version-1"
+uri     = "test-uri-1"
+sha256  = "test-sha256-1"
+`), 0600)).To(Succeed())
+		})
+
+		it("logs a summary by default", func() {
+			progress := &bytes.Buffer{}
+
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "amd64",
+				SHA256:          "test-sha256-2",
+				URI:             "test-uri-2",
+				Version:         "test-version-2",
+				VersionPattern:  `^test-version-[\d]$`,
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler), carton.WithProgressWriter(progress))
+
+			Expect(progress.String()).To(ContainSubstring("test-uri-2"))
+		})
+
+		it("does not log a summary but still updates", func() {
+			progress := &bytes.Buffer{}
+
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "amd64",
+				SHA256:          "test-sha256-2",
+				URI:             "test-uri-2",
+				Version:         "test-version-2",
+				VersionPattern:  `^test-version-[\d]$`,
+				Quiet:           true,
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler), carton.WithProgressWriter(progress))
+
+			Expect(progress.String()).To(BeEmpty())
+			Expect(os.ReadFile(path)).To(ContainSubstring("test-uri-2"))
+		})
+	})
+
+	it("updates dependencies in a metadata subsection", func() {
+		Expect(os.WriteFile(path, []byte(`api = "0.7"
+[[metadata.dependencies.jvm]]
+id      = "test-id"
+version = "test-version-1"
+uri     = "test-uri-1"
+sha256  = "test-sha256-1"
+
+[[metadata.dependencies.tools]]
+id      = "test-id"
+version = "test-version-1"
+uri     = "test-uri-1"
+sha256  = "test-sha256-1"
+`), 0600)).To(Succeed())
+
+		d := carton.BuildModuleDependency{
+			BuildModulePath: path,
+			ID:              "test-id",
+			Arch:            "amd64",
+			SHA256:          "test-sha256-2",
+			URI:             "test-uri-2",
+			Version:         "test-version-2",
+			VersionPattern:  `test-version-[\d]`,
+			MetadataSubkey:  "jvm",
+		}
+
+		d.Update(carton.WithExitHandler(exitHandler))
+
+		exitHandler.AssertNotCalled(t, "Error", mock.Anything)
+		Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`api = "0.7"
+[[metadata.dependencies.jvm]]
+id      = "test-id"
+version = "test-version-2"
+uri     = "test-uri-2"
+sha256  = "test-sha256-2"
+
+[[metadata.dependencies.tools]]
+id      = "test-id"
+version = "test-version-1"
+uri     = "test-uri-1"
+sha256  = "test-sha256-1"
+`))
+	})
+
+	it("fails when the metadata subsection does not exist", func() {
+		Expect(os.WriteFile(path, []byte(`[[metadata.dependencies.jvm]]
+id      = "test-id"
+version = "test-version-1"
+`), 0600)).To(Succeed())
+
+		d := carton.BuildModuleDependency{
+			BuildModulePath: path,
+			ID:              "test-id",
+			Arch:            "amd64",
+			SHA256:          "test-sha256-2",
+			URI:             "test-uri-2",
+			Version:         "test-version-2",
+			VersionPattern:  `test-version-[\d]`,
+			MetadataSubkey:  "tools",
+		}
+
+		d.Update(carton.WithExitHandler(exitHandler))
+
+		exitHandler.AssertCalled(t, "Error", mock.MatchedBy(func(err error) bool {
+			return err.Error() == "unable to find dependencies.tools block"
+		}))
+	})
+
+	context("match uri", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(path, []byte(`api = "0.7"
+[[metadata.dependencies]]
+id      = "test-id"
+version = "1.0.0"
+uri     = "test-uri-1"
+sha256  = "test-sha256-1"
+purl    = "pkg:generic/test@1.0.0"
+cpes    = ["cpe:2.3:a:test:test:1.0.0:*:*:*:*:*:*:*"]
+
+[[metadata.dependencies]]
+id      = "test-id"
+version = "1.0.0"
+uri     = "test-uri-other"
+sha256  = "test-sha256-other"
+`), 0600)).To(Succeed())
+		})
+
+		it("updates only the dependency with the uri", func() {
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "amd64",
+				SHA256:          "test-sha256-2",
+				URI:             "test-uri-2",
+				Version:         "2.0.0",
+				PURL:            "2.0.0",
+				CPE:             "2.0.0",
+				MatchURI:        "test-uri-1",
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler))
+
+			exitHandler.AssertNotCalled(t, "Error", mock.Anything)
+			Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`api = "0.7"
+[[metadata.dependencies]]
+id      = "test-id"
+version = "2.0.0"
+uri     = "test-uri-2"
+sha256  = "test-sha256-2"
+purl    = "pkg:generic/test@2.0.0"
+cpes    = ["cpe:2.3:a:test:test:2.0.0:*:*:*:*:*:*:*"]
+
+[[metadata.dependencies]]
+id      = "test-id"
+version = "1.0.0"
+uri     = "test-uri-other"
+sha256  = "test-sha256-other"
+`))
+		})
+
+		it("leaves the purl unchanged without a purl", func() {
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "amd64",
+				SHA256:          "test-sha256-2",
+				URI:             "test-uri-2",
+				Version:         "2.0.0",
+				CPE:             "2.0.0",
+				MatchURI:        "test-uri-1",
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler))
+
+			exitHandler.AssertNotCalled(t, "Error", mock.Anything)
+			Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`api = "0.7"
+[[metadata.dependencies]]
+id      = "test-id"
+version = "2.0.0"
+uri     = "test-uri-2"
+sha256  = "test-sha256-2"
+purl    = "pkg:generic/test@1.0.0"
+cpes    = ["cpe:2.3:a:test:test:2.0.0:*:*:*:*:*:*:*"]
+
+[[metadata.dependencies]]
+id      = "test-id"
+version = "1.0.0"
+uri     = "test-uri-other"
+sha256  = "test-sha256-other"
+`))
+		})
+
+		it("fails when more than one dependency has the uri", func() {
+			Expect(os.WriteFile(path, []byte(`[[metadata.dependencies]]
+id      = "test-id"
+version = "1.0.0"
+uri     = "test-uri-1"
+sha256  = "test-sha256-1"
+
+[[metadata.dependencies]]
+id      = "test-id"
+version = "1.0.1"
+uri     = "test-uri-1"
+sha256  = "test-sha256-1"
+`), 0600)).To(Succeed())
+
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "amd64",
+				SHA256:          "test-sha256-2",
+				URI:             "test-uri-2",
+				Version:         "2.0.0",
+				MatchURI:        "test-uri-1",
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler))
+
+			exitHandler.AssertCalled(t, "Error", mock.MatchedBy(func(err error) bool {
+				return err.Error() == "more than one test-id dependency has uri test-uri-1"
+			}))
+			Expect(os.ReadFile(path)).NotTo(ContainSubstring("2.0.0"))
+		})
+	})
+
+	context("add if missing", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(path, []byte(`api = "0.7"
+[[metadata.dependencies]]
+id      = "test-id"
+name    = "Test Name"
+version = "1.0.0"
+uri     = "test-uri-amd64"
+sha256  = "test-sha256-amd64"
+purl    = "pkg:generic/test@1.0.0?arch=amd64"
+cpes    = ["cpe:2.3:a:test:test:1.0.0:*:*:*:*:*:*:*"]
+stacks  = ["*"]
+`), 0600)).To(Succeed())
+		})
+
+		it("adds an arm64 dependency copied from the amd64 one", func() {
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "arm64",
+				SHA256:          "test-sha256-arm64",
+				URI:             "test-uri-arm64",
+				Version:         "1.0.0",
+				VersionPattern:  `^1\.0\.0$`,
+				PURL:            "1.0.0",
+				CPE:             "1.0.0",
+				AddIfMissing:    true,
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler))
+
+			exitHandler.AssertNotCalled(t, "Error", mock.Anything)
+			Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`api = "0.7"
+[[metadata.dependencies]]
+id      = "test-id"
+name    = "Test Name"
+version = "1.0.0"
+uri     = "test-uri-amd64"
+sha256  = "test-sha256-amd64"
+purl    = "pkg:generic/test@1.0.0?arch=amd64"
+cpes    = ["cpe:2.3:a:test:test:1.0.0:*:*:*:*:*:*:*"]
+stacks  = ["*"]
+
+[[metadata.dependencies]]
+id      = "test-id"
+name    = "Test Name"
+version = "1.0.0"
+uri     = "test-uri-arm64"
+sha256  = "test-sha256-arm64"
+purl    = "pkg:generic/test@1.0.0?arch=arm64"
+cpes    = ["cpe:2.3:a:test:test:1.0.0:*:*:*:*:*:*:*"]
+stacks  = ["*"]
+`))
+		})
+
+		it("does not add a dependency when one matches", func() {
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "amd64",
+				SHA256:          "test-sha256-2",
+				URI:             "test-uri-2",
+				Version:         "2.0.0",
+				VersionPattern:  `^1\.0\.0$`,
+				PURL:            "2.0.0",
+				CPE:             "2.0.0",
+				AddIfMissing:    true,
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler))
+
+			exitHandler.AssertNotCalled(t, "Error", mock.Anything)
+			body, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(strings.Count(string(body), "[[metadata.dependencies]]")).To(Equal(1))
+			Expect(string(body)).To(ContainSubstring(`purl = "pkg:generic/test@2.0.0?arch=amd64"`))
+		})
+
+		it("adds a dependency with only the supplied fields when there is none to copy", func() {
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "other-id",
+				Arch:            "arm64",
+				SHA256:          "other-sha256",
+				URI:             "other-uri",
+				Version:         "3.0.0",
+				VersionPattern:  `^3\.0\.0$`,
+				PURL:            "pkg:generic/other@3.0.0",
+				CPE:             "cpe:2.3:a:other:other:3.0.0:*:*:*:*:*:*:*",
+				AddIfMissing:    true,
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler))
+
+			exitHandler.AssertNotCalled(t, "Error", mock.Anything)
+			Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`api = "0.7"
+[[metadata.dependencies]]
+id      = "test-id"
+name    = "Test Name"
+version = "1.0.0"
+uri     = "test-uri-amd64"
+sha256  = "test-sha256-amd64"
+purl    = "pkg:generic/test@1.0.0?arch=amd64"
+cpes    = ["cpe:2.3:a:test:test:1.0.0:*:*:*:*:*:*:*"]
+stacks  = ["*"]
+
+[[metadata.dependencies]]
+id      = "other-id"
+version = "3.0.0"
+uri     = "other-uri"
+sha256  = "other-sha256"
+purl    = "pkg:generic/other@3.0.0?arch=arm64"
+cpes    = ["cpe:2.3:a:other:other:3.0.0:*:*:*:*:*:*:*"]
+`))
+		})
+	})
+
+	context("require match", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(path, []byte(`api = "0.7"
+[[metadata.dependencies]]
+id      = "test-id"
+version = "1.0.0"
+uri     = "test-uri-1"
+sha256  = "test-sha256-1"
+`), 0600)).To(Succeed())
+		})
+
+		it("fails when no dependency matches", func() {
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "arm64",
+				SHA256:          "test-sha256-2",
+				URI:             "test-uri-2",
+				Version:         "2.0.0",
+				VersionPattern:  `^1\.0\.0$`,
+				RequireMatch:    true,
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler))
+
+			exitHandler.AssertCalled(t, "Error", mock.MatchedBy(func(err error) bool {
+				return err.Error() == `no test-id dependency matched arch arm64 and version pattern ^1\.0\.0$`
+			}))
+			Expect(os.ReadFile(path)).NotTo(ContainSubstring("2.0.0"))
+		})
+
+		it("succeeds when a dependency matches", func() {
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "amd64",
+				SHA256:          "test-sha256-2",
+				URI:             "test-uri-2",
+				Version:         "2.0.0",
+				VersionPattern:  `^1\.0\.0$`,
+				RequireMatch:    true,
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler))
+
+			exitHandler.AssertNotCalled(t, "Error", mock.Anything)
+			Expect(os.ReadFile(path)).To(ContainSubstring(`version = "2.0.0"`))
+		})
+	})
+
+	context("name", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(path, []byte(`api = "0.7"
+[[metadata.dependencies]]
+id      = "test-id"
+name    = "Test JRE 17"
+version = "17.0.1"
+uri     = "test-uri-1"
+sha256  = "test-sha256-1"
+`), 0600)).To(Succeed())
+		})
+
+		it("replaces the version pattern in the name", func() {
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "amd64",
+				SHA256:          "test-sha256-2",
+				URI:             "test-uri-2",
+				Version:         "21.0.1",
+				VersionPattern:  `^17\.`,
+				Name:            "21",
+				NamePattern:     `\d+$`,
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler))
+
+			exitHandler.AssertNotCalled(t, "Error", mock.Anything)
+			Expect(os.ReadFile(path)).To(ContainSubstring(`name = "Test JRE 21"`))
+		})
+
+		it("leaves the name untouched when it is not set", func() {
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "amd64",
+				SHA256:          "test-sha256-2",
+				URI:             "test-uri-2",
+				Version:         "21.0.1",
+				VersionPattern:  `^17\.`,
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler))
+
+			exitHandler.AssertNotCalled(t, "Error", mock.Anything)
+			Expect(os.ReadFile(path)).To(ContainSubstring(`name = "Test JRE 17"`))
+		})
+	})
+
+	context("eol id", func() {
+		it.Before(func() {
+			httpmock.Activate()
+			httpmock.RegisterResponder(http.MethodGet, "https://endoflife.date/api/foo.json", httpmock.NewBytesResponder(200, []byte(`[
+	{ "cycle": "2.0", "eol": "2030-06-30" }
+]`)))
+
+			Expect(os.WriteFile(path, []byte(`api = "0.7"
+[[metadata.dependencies]]
+id      = "test-id"
+version = "1.0.0"
+uri     = "test-uri-1"
+sha256  = "test-sha256-1"
+`), 0600)).To(Succeed())
+		})
+
+		it.After(func() {
+			httpmock.DeactivateAndReset()
+		})
+
+		it("sets the deprecation date of the updated dependency", func() {
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "amd64",
+				SHA256:          "test-sha256-2",
+				URI:             "test-uri-2",
+				Version:         "2.0.1",
+				VersionPattern:  `^1\.0\.0$`,
+				EolID:           "foo",
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler))
+
+			exitHandler.AssertNotCalled(t, "Error", mock.Anything)
+			Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`api = "0.7"
+[[metadata.dependencies]]
+id               = "test-id"
+version          = "2.0.1"
+uri              = "test-uri-2"
+sha256           = "test-sha256-2"
+deprecation_date = "2030-06-30T00:00:00Z"
+`))
+		})
+	})
+
+	context("fail on no change", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(path, []byte(`api = "0.7"
+[[metadata.dependencies]]
+id      = "test-id"
+version = "1.0.0"
+uri     = "test-uri-1"
+sha256  = "test-sha256-1"
+`), 0600)).To(Succeed())
+		})
+
+		it("fails only when the build module is unchanged", func() {
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "amd64",
+				SHA256:          "test-sha256-2",
+				URI:             "test-uri-2",
+				Version:         "2.0.0",
+				VersionPattern:  `^\d+\.\d+\.\d+$`,
+				FailOnNoChange:  true,
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler))
+
+			exitHandler.AssertNotCalled(t, "Error", mock.Anything)
+			Expect(os.ReadFile(path)).To(ContainSubstring(`version = "2.0.0"`))
+
+			d.Update(carton.WithExitHandler(exitHandler))
+
+			exitHandler.AssertCalled(t, "Error", mock.MatchedBy(func(err error) bool {
+				return errors.Is(err, internal.ErrNoChange)
+			}))
+		})
+	})
+
+	it("updates indented dependency", func() {
+		Expect(os.WriteFile(path, []byte(`# it should preserve
+#   these comments
+#      exactly
+
+api = "0.6"
+[buildpack]
+id = "some-buildpack"
+name = "Some Buildpack"
+version = "1.2.3"
+
+[[metadata.dependencies]]
+  id      = "test-id"
+  name    = "Test Name"
+  version = "test-version-1"
+  uri     = "test-uri-1"
+  sha256  = "test-sha256-1"
+  stacks  = [ "test-stack" ]
+`), 0600)).To(Succeed())
+
+		d := carton.BuildModuleDependency{
+			BuildModulePath: path,
+			ID:              "test-id",
+			Arch:            "amd64",
+			SHA256:          "test-sha256-2",
+			URI:             "test-uri-2",
+			Version:         "test-version-2",
+			VersionPattern:  `test-version-[\d]`,
+		}
+
+		d.Update(carton.WithExitHandler(exitHandler))
+
+		body, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(HavePrefix(`# it should preserve
+#   these comments
+#      exactly
+
+api = "0.6"`))
+		Expect(body).To(libpakTesting.MatchTOML(`api = "0.6"
+[buildpack]
+id = "some-buildpack"
+name = "Some Buildpack"
+version = "1.2.3"
+
+[[metadata.dependencies]]
+  id      = "test-id"
+  name    = "Test Name"
+  version = "test-version-2"
+  uri     = "test-uri-2"
+  sha256  = "test-sha256-2"
+  stacks  = [ "test-stack" ]
+`))
+	})
+
+	it("replaces the stacks when they are set", func() {
+		Expect(os.WriteFile(path, []byte(`api = "0.6"
+[buildpack]
+id = "some-buildpack"
+
+[[metadata.dependencies]]
+id      = "test-id"
+version = "test-version-1"
+uri     = "test-uri-1"
+sha256  = "test-sha256-1"
+stacks  = [ "io.buildpacks.stacks.bionic" ]
+`), 0600)).To(Succeed())
+
+		d := carton.BuildModuleDependency{
+			BuildModulePath: path,
+			ID:              "test-id",
+			Arch:            "amd64",
+			SHA256:          "test-sha256-2",
+			URI:             "test-uri-2",
+			Version:         "test-version-2",
+			VersionPattern:  `^test-version-[\d]$`,
+			Stacks:          []string{"*"},
+		}
+
+		d.Update(carton.WithExitHandler(exitHandler), carton.WithProgressWriter(&bytes.Buffer{}))
+
+		Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`api = "0.6"
+[buildpack]
+id = "some-buildpack"
+
+[[metadata.dependencies]]
+id      = "test-id"
+version = "test-version-2"
+uri     = "test-uri-2"
+sha256  = "test-sha256-2"
+stacks  = [ "*" ]
+`))
+	})
+
+	context("version constraint", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(path, []byte(`api = "0.6"
+[buildpack]
+id = "some-buildpack"
+
+[[metadata.dependencies]]
+id      = "test-id"
+version = "17.0.1"
+uri     = "test-uri-17"
+sha256  = "test-sha256-17"
+stacks  = [ "test-stack" ]
+
+[[metadata.dependencies]]
+id      = "test-id"
+version = "21.0.1"
+uri     = "test-uri-21"
+sha256  = "test-sha256-21"
+stacks  = [ "test-stack" ]
+
+[[metadata.dependencies]]
+id      = "test-id"
+version = "jdk17u-b12"
+uri     = "test-uri-b12"
+sha256  = "test-sha256-b12"
+stacks  = [ "test-stack" ]
+`), 0600)).To(Succeed())
+		})
+
+		it("updates the dependencies in the SemVer range and skips versions that are not SemVer", func() {
+			progress := &bytes.Buffer{}
+			d := carton.BuildModuleDependency{
+				BuildModulePath:   path,
+				ID:                "test-id",
+				Arch:              "amd64",
+				SHA256:            "test-sha256-17.0.2",
+				URI:               "test-uri-17.0.2",
+				Version:           "17.0.2",
+				VersionConstraint: ">=17.0.0 <18.0.0",
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler), carton.WithProgressWriter(progress))
+
+			exitHandler.AssertNotCalled(t, "Error", mock.Anything)
+			Expect(progress.String()).To(ContainSubstring("Warning: skipping test-id jdk17u-b12, its version is not SemVer"))
+			Expect(progress.String()).NotTo(ContainSubstring("is not anchored"))
+			Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`api = "0.6"
+[buildpack]
+id = "some-buildpack"
+
+[[metadata.dependencies]]
+id      = "test-id"
+version = "17.0.2"
+uri     = "test-uri-17.0.2"
+sha256  = "test-sha256-17.0.2"
+stacks  = [ "test-stack" ]
+
+[[metadata.dependencies]]
+id      = "test-id"
+version = "21.0.1"
+uri     = "test-uri-21"
+sha256  = "test-sha256-21"
+stacks  = [ "test-stack" ]
+
+[[metadata.dependencies]]
+id      = "test-id"
+version = "jdk17u-b12"
+uri     = "test-uri-b12"
+sha256  = "test-sha256-b12"
+stacks  = [ "test-stack" ]
+`))
+		})
+
+		it("fails for an invalid constraint", func() {
+			d := carton.BuildModuleDependency{
+				BuildModulePath:   path,
+				ID:                "test-id",
+				Arch:              "amd64",
+				SHA256:            "test-sha256-17.0.2",
+				URI:               "test-uri-17.0.2",
+				Version:           "17.0.2",
+				VersionConstraint: ">=seventeen",
+			}
+
+			d.Update(carton.WithExitHandler(exitHandler), carton.WithProgressWriter(&bytes.Buffer{}))
+
+			exitHandler.AssertCalled(t, "Error", mock.MatchedBy(func(err error) bool {
+				return strings.Contains(err.Error(), "unable to parse version constraint >=seventeen")
+			}))
+		})
+	})
+
+	context("update bytes", func() {
+		var in []byte
+
+		it.Before(func() {
+			in = []byte(`api = "0.6"
+[buildpack]
+id = "some-buildpack"
+
+[[metadata.dependencies]]
+id      = "test-id"
+name    = "Test Name"
+version = "test-version-1"
+uri     = "test-uri-1"
+sha256  = "test-sha256-1"
+stacks  = [ "test-stack" ]
+`)
+		})
+
+		it("updates the dependency in memory", func() {
+			d := carton.BuildModuleDependency{
+				ID:             "test-id",
+				Arch:           "amd64",
+				SHA256:         "test-sha256-2",
+				URI:            "test-uri-2",
+				Version:        "test-version-2",
+				VersionPattern: `^test-version-[\d]$`,
+			}
+
+			out, err := d.UpdateBytes(in)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(out).To(libpakTesting.MatchTOML(`api = "0.6"
+[buildpack]
+id = "some-buildpack"
+
+[[metadata.dependencies]]
+id      = "test-id"
+name    = "Test Name"
+version = "test-version-2"
+uri     = "test-uri-2"
+sha256  = "test-sha256-2"
+stacks  = [ "test-stack" ]
+`))
+		})
+
+		it("does not touch the build module path", func() {
+			d := carton.BuildModuleDependency{
+				BuildModulePath: path,
+				ID:              "test-id",
+				Arch:            "amd64",
+				SHA256:          "test-sha256-2",
+				URI:             "test-uri-2",
+				Version:         "test-version-2",
+				VersionPattern:  `^test-version-[\d]$`,
+			}
+
+			_, err := d.UpdateBytes(in)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(os.ReadFile(path)).To(BeEmpty())
+		})
+
+		it("returns an error instead of exiting", func() {
+			d := carton.BuildModuleDependency{
+				ID:             "test-id",
+				Arch:           "amd64",
+				SHA256:         "test-sha256-2",
+				URI:            "test-uri-2",
+				Version:        "test-version-2",
+				VersionPattern: `^test-version-[\d]$`,
+				RequireMatch:   true,
+				MatchURI:       "other-uri",
+			}
+
+			_, err := d.UpdateBytes(in)
+			Expect(err).To(MatchError("no test-id dependency matched arch amd64 and uri other-uri"))
+		})
+
+		it("updates an inline array of dependencies", func() {
+			d := carton.BuildModuleDependency{
+				ID:             "test-id",
+				Arch:           "amd64",
+				SHA256:         "test-sha256-2",
+				URI:            "test-uri-2",
+				Version:        "test-version-2",
+				VersionPattern: `^test-version-[\d]$`,
+			}
+
+			out, err := d.UpdateBytes([]byte(`api = "0.6"
+
+[metadata]
+dependencies = [
+  { id = "test-id", version = "test-version-1", uri = "test-uri-1", sha256 = "test-sha256-1" },
+]
+`))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(out).To(libpakTesting.MatchTOML(`api = "0.6"
+
+[[metadata.dependencies]]
+id      = "test-id"
+version = "test-version-2"
+uri     = "test-uri-2"
+sha256  = "test-sha256-2"
+`))
+		})
+
+		it("returns an error for invalid toml", func() {
+			d := carton.BuildModuleDependency{
+				ID:             "test-id",
+				Version:        "test-version-2",
+				VersionPattern: `^test-version-[\d]$`,
+			}
+
+			_, err := d.UpdateBytes([]byte("not = toml = here"))
+			Expect(err).To(MatchError(ContainSubstring("unable to decode md")))
+		})
+	})
 }
@@ -0,0 +1,199 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package carton
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/BurntSushi/toml"
+)
+
+// DependencyChange is a single difference between the dependencies of two build modules.
+type DependencyChange struct {
+	ID   string `json:"id"`
+	Arch string `json:"arch"`
+
+	// Status is one of added, removed or changed.
+	Status string `json:"status"`
+
+	// Fields are the fields of a changed dependency that differ, out of version, uri and sha256.
+	Fields []string `json:"fields,omitempty"`
+
+	OldVersion string `json:"oldVersion,omitempty"`
+	NewVersion string `json:"newVersion,omitempty"`
+	OldURI     string `json:"oldUri,omitempty"`
+	NewURI     string `json:"newUri,omitempty"`
+	OldSHA256  string `json:"oldSha256,omitempty"`
+	NewSHA256  string `json:"newSha256,omitempty"`
+}
+
+// BuildModuleDiff compares the dependencies of two build modules.
+type BuildModuleDiff struct {
+	OldPath string
+	NewPath string
+}
+
+type diffKey struct {
+	id   string
+	arch string
+}
+
+type diffDependency struct {
+	version string
+	uri     string
+	sha256  string
+}
+
+// Diff returns the dependency changes between the old and new build module, ordered by id and arch. Dependencies are
+// grouped by id and arch. Within a group, dependencies with the same version are compared with each other and the
+// rest are paired in the order they appear, any left over are added or removed.
+func (d BuildModuleDiff) Diff() ([]DependencyChange, error) {
+	o, err := readDiffDependencies(d.OldPath)
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := readDiffDependencies(d.NewPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []diffKey
+	for k := range o {
+		keys = append(keys, k)
+	}
+	for k := range n {
+		if _, found := o[k]; !found {
+			keys = append(keys, k)
+		}
+	}
+	slices.SortFunc(keys, func(a, b diffKey) int {
+		return cmp.Or(cmp.Compare(a.id, b.id), cmp.Compare(a.arch, b.arch))
+	})
+
+	var changes []DependencyChange
+	for _, k := range keys {
+		changes = append(changes, diffGroup(k.id, k.arch, o[k], n[k])...)
+	}
+
+	return changes, nil
+}
+
+func diffGroup(id string, arch string, o []diffDependency, n []diffDependency) []DependencyChange {
+	var changes []DependencyChange
+	n = slices.Clone(n)
+
+	// pair the dependencies that kept their version first, so that a bump of one version line is not mistaken for
+	// changes to all of them
+	var unmatched []diffDependency
+	for _, od := range o {
+		found := false
+		for i, nd := range n {
+			if nd.version == od.version {
+				changes = appendChange(changes, id, arch, od, nd)
+				n = slices.Delete(n, i, i+1)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			unmatched = append(unmatched, od)
+		}
+	}
+
+	for i, od := range unmatched {
+		if i < len(n) {
+			changes = appendChange(changes, id, arch, od, n[i])
+		} else {
+			changes = append(changes, DependencyChange{
+				ID: id, Arch: arch, Status: ChangeRemoved,
+				OldVersion: od.version, OldURI: od.uri, OldSHA256: od.sha256,
+			})
+		}
+	}
+
+	for i := len(unmatched); i < len(n); i++ {
+		changes = append(changes, DependencyChange{
+			ID: id, Arch: arch, Status: ChangeAdded,
+			NewVersion: n[i].version, NewURI: n[i].uri, NewSHA256: n[i].sha256,
+		})
+	}
+
+	return changes
+}
+
+// appendChange appends a changed DependencyChange if any field of o and n differs
+func appendChange(changes []DependencyChange, id string, arch string, o diffDependency, n diffDependency) []DependencyChange {
+	c := DependencyChange{
+		ID: id, Arch: arch, Status: ChangeChanged,
+		OldVersion: o.version, NewVersion: n.version,
+		OldURI: o.uri, NewURI: n.uri,
+		OldSHA256: o.sha256, NewSHA256: n.sha256,
+	}
+
+	if o.version != n.version {
+		c.Fields = append(c.Fields, "version")
+	}
+	if o.uri != n.uri {
+		c.Fields = append(c.Fields, "uri")
+	}
+	if o.sha256 != n.sha256 {
+		c.Fields = append(c.Fields, "sha256")
+	}
+
+	if len(c.Fields) == 0 {
+		return changes
+	}
+
+	return append(changes, c)
+}
+
+// readDiffDependencies reads the dependencies of a build module, grouped by id and arch
+func readDiffDependencies(path string) (map[diffKey][]diffDependency, error) {
+	md := make(map[string]interface{})
+	if _, err := toml.DecodeFile(path, &md); err != nil {
+		return nil, fmt.Errorf("unable to decode md %s\n%w", path, err)
+	}
+
+	dependencies, err := buildModuleDependencies(md, "")
+	if err != nil {
+		return nil, err
+	}
+
+	groups := map[diffKey][]diffDependency{}
+	for _, dep := range dependencies {
+		id, _ := dep["id"].(string)
+		arch := dependencyArch(dep, func(arch string) string { return arch })
+
+		d := diffDependency{}
+		d.version, _ = dep["version"].(string)
+		d.uri, _ = dep["uri"].(string)
+		if checksum, ok := dep["checksum"].(string); ok {
+			d.sha256 = checksum
+		} else {
+			d.sha256, _ = dep["sha256"].(string)
+		}
+
+		key := diffKey{id: id, arch: arch}
+		groups[key] = append(groups[key], d)
+	}
+
+	return groups, nil
+}
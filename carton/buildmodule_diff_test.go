@@ -0,0 +1,140 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package carton_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libpak-tools/carton"
+)
+
+func testBuildModuleDiff(t *testing.T, _ spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		oldPath string
+		newPath string
+	)
+
+	it.Before(func() {
+		dir := t.TempDir()
+		oldPath = filepath.Join(dir, "old-buildpack.toml")
+		newPath = filepath.Join(dir, "new-buildpack.toml")
+	})
+
+	it("reports added, removed and changed dependencies by id and arch", func() {
+		Expect(os.WriteFile(oldPath, []byte(`
+[[metadata.dependencies]]
+id      = "jdk"
+version = "17.0.1"
+uri     = "jdk-17.0.1-amd64"
+sha256  = "sha-17.0.1-amd64"
+purl    = "pkg:generic/jdk@17.0.1?arch=amd64"
+
+[[metadata.dependencies]]
+id      = "jdk"
+version = "21.0.1"
+uri     = "jdk-21.0.1-amd64"
+sha256  = "sha-21.0.1-amd64"
+purl    = "pkg:generic/jdk@21.0.1?arch=amd64"
+
+[[metadata.dependencies]]
+id      = "jdk"
+version = "17.0.1"
+uri     = "jdk-17.0.1-arm64"
+sha256  = "sha-17.0.1-arm64"
+purl    = "pkg:generic/jdk@17.0.1?arch=arm64"
+
+[[metadata.dependencies]]
+id      = "jre"
+version = "8.0.1"
+uri     = "jre-8.0.1"
+sha256  = "sha-jre-8.0.1"
+`), 0600)).To(Succeed())
+
+		Expect(os.WriteFile(newPath, []byte(`
+[[metadata.dependencies]]
+id      = "jdk"
+version = "17.0.2"
+uri     = "jdk-17.0.2-amd64"
+sha256  = "sha-17.0.2-amd64"
+purl    = "pkg:generic/jdk@17.0.2?arch=amd64"
+
+[[metadata.dependencies]]
+id      = "jdk"
+version = "21.0.1"
+uri     = "jdk-21.0.1-amd64"
+sha256  = "sha-21.0.1-amd64"
+purl    = "pkg:generic/jdk@21.0.1?arch=amd64"
+
+[[metadata.dependencies]]
+id       = "jdk"
+version  = "17.0.1"
+uri      = "jdk-17.0.1-arm64-rebuilt"
+checksum = "sha256:sha-17.0.1-arm64-rebuilt"
+purl     = "pkg:generic/jdk@17.0.1?arch=arm64"
+
+[[metadata.dependencies]]
+id      = "native-image"
+version = "21.0.1"
+uri     = "native-image-21.0.1"
+sha256  = "sha-native-image-21.0.1"
+`), 0600)).To(Succeed())
+
+		Expect(carton.BuildModuleDiff{OldPath: oldPath, NewPath: newPath}.Diff()).To(Equal([]carton.DependencyChange{
+			{
+				ID: "jdk", Arch: "amd64", Status: carton.ChangeChanged, Fields: []string{"version", "uri", "sha256"},
+				OldVersion: "17.0.1", NewVersion: "17.0.2",
+				OldURI: "jdk-17.0.1-amd64", NewURI: "jdk-17.0.2-amd64",
+				OldSHA256: "sha-17.0.1-amd64", NewSHA256: "sha-17.0.2-amd64",
+			},
+			{
+				ID: "jdk", Arch: "arm64", Status: carton.ChangeChanged, Fields: []string{"uri", "sha256"},
+				OldVersion: "17.0.1", NewVersion: "17.0.1",
+				OldURI: "jdk-17.0.1-arm64", NewURI: "jdk-17.0.1-arm64-rebuilt",
+				OldSHA256: "sha-17.0.1-arm64", NewSHA256: "sha256:sha-17.0.1-arm64-rebuilt",
+			},
+			{
+				ID: "jre", Arch: "amd64", Status: carton.ChangeRemoved,
+				OldVersion: "8.0.1", OldURI: "jre-8.0.1", OldSHA256: "sha-jre-8.0.1",
+			},
+			{
+				ID: "native-image", Arch: "amd64", Status: carton.ChangeAdded,
+				NewVersion: "21.0.1", NewURI: "native-image-21.0.1", NewSHA256: "sha-native-image-21.0.1",
+			},
+		}))
+	})
+
+	it("reports no changes for identical dependencies", func() {
+		c := []byte(`
+[[metadata.dependencies]]
+id      = "jdk"
+version = "17.0.1"
+uri     = "jdk-17.0.1"
+sha256  = "sha-17.0.1"
+`)
+		Expect(os.WriteFile(oldPath, c, 0600)).To(Succeed())
+		Expect(os.WriteFile(newPath, c, 0600)).To(Succeed())
+
+		Expect(carton.BuildModuleDiff{OldPath: oldPath, NewPath: newPath}.Diff()).To(BeEmpty())
+	})
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package carton
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/paketo-buildpacks/libpak/v2/log"
+	"github.com/paketo-buildpacks/libpak/v2/utils"
+
+	"github.com/paketo-buildpacks/libpak-tools/internal"
+)
+
+// BuildModuleEOL refreshes the end of life date of build module dependencies without changing anything else about
+// them.
+type BuildModuleEOL struct {
+	BuildModulePath string
+	ID              string
+	VersionPattern  string
+	EolID           string
+
+	// EolCache is the path to a JSON cache of release cycles that is read before, and updated after, looking up EOL
+	// dates on endoflife.date
+	EolCache string
+
+	// TOMLIndent is the number of spaces nested tables are indented with, when zero the encoder default is used
+	TOMLIndent int
+
+	// FailOnNoChange fails the update when the build module is byte-identical after it is applied
+	FailOnNoChange bool
+}
+
+func (b BuildModuleEOL) Refresh(options ...Option) {
+	config := Config{
+		exitHandler: utils.NewExitHandler(),
+		progress:    os.Stderr,
+	}
+
+	for _, option := range options {
+		config = option(config)
+	}
+
+	logger := log.NewPaketoLogger(config.progress)
+	_, _ = fmt.Fprintf(logger.TitleWriter(), "\n%s\n", log.FormatIdentity(b.ID, b.VersionPattern))
+	logger.Headerf("EOL ID:       %s", b.EolID)
+
+	versionExp, err := regexp.Compile(b.VersionPattern)
+	if err != nil {
+		config.exitHandler.Error(fmt.Errorf("unable to compile version regex %s\n%w", b.VersionPattern, err))
+		return
+	}
+
+	if err := internal.UpdateTOMLFile(b.BuildModulePath, internal.TOMLOptions{Indent: b.TOMLIndent, FailOnNoChange: b.FailOnNoChange}, func(md map[string]interface{}) error {
+		dependencies, err := buildModuleDependencies(md, "")
+		if err != nil {
+			return err
+		}
+
+		for _, dep := range dependencies {
+			if depID, ok := dep["id"].(string); !ok || depID != b.ID {
+				continue
+			}
+
+			depVersion, ok := dep["version"].(string)
+			if !ok || !versionExp.MatchString(depVersion) {
+				continue
+			}
+
+			eolDate, err := internal.GetEolDateWithCache(b.EolID, depVersion, b.EolCache)
+			if err != nil {
+				return fmt.Errorf("unable to fetch eol date for %s\n%w", depVersion, err)
+			}
+
+			if eolDate != "" {
+				logger.Bodyf("%s: %s", depVersion, eolDate)
+				updateEOLDate(dep, eolDate)
+			}
+		}
+
+		return nil
+	}); err != nil {
+		config.exitHandler.Error(err)
+		return
+	}
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package carton_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildpacks/libcnb/v2/mocks"
+	"github.com/jarcoal/httpmock"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/stretchr/testify/mock"
+
+	libpakTesting "github.com/paketo-buildpacks/libpak/v2/testing"
+
+	"github.com/paketo-buildpacks/libpak-tools/carton"
+)
+
+func testBuildModuleEOL(t *testing.T, _ spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		exitHandler *mocks.ExitHandler
+		path        string
+	)
+
+	it.Before(func() {
+		httpmock.Activate()
+		httpmock.RegisterResponder(http.MethodGet, "https://endoflife.date/api/foo.json", httpmock.NewBytesResponder(200, []byte(`[
+	{ "cycle": "10.1", "eol": "2027-12-31" },
+	{ "cycle": "10.0", "eol": "2026-12-31" }
+]`)))
+
+		exitHandler = &mocks.ExitHandler{}
+		exitHandler.On("Error", mock.Anything)
+
+		path = filepath.Join(t.TempDir(), "buildpack.toml")
+	})
+
+	it.After(func() {
+		httpmock.DeactivateAndReset()
+	})
+
+	it("refreshes only the eol date of matching dependencies", func() {
+		Expect(os.WriteFile(path, []byte(`api = "0.7"
+[[metadata.dependencies]]
+id               = "test-id"
+version          = "10.0.1"
+uri              = "test-uri-1"
+sha256           = "test-sha256-1"
+deprecation_date = "2025-01-01T00:00:00Z"
+
+[[metadata.dependencies]]
+id       = "test-id"
+version  = "10.1.2"
+uri      = "test-uri-2"
+checksum = "sha256:test-sha256-2"
+eol-date = "2025-01-01T00:00:00Z"
+
+[[metadata.dependencies]]
+id      = "other-id"
+version = "10.0.1"
+uri     = "test-uri-3"
+sha256  = "test-sha256-3"
+`), 0600)).To(Succeed())
+
+		b := carton.BuildModuleEOL{
+			BuildModulePath: path,
+			ID:              "test-id",
+			VersionPattern:  `^10\.`,
+			EolID:           "foo",
+		}
+
+		b.Refresh(carton.WithExitHandler(exitHandler))
+
+		exitHandler.AssertNotCalled(t, "Error", mock.Anything)
+		Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`api = "0.7"
+[[metadata.dependencies]]
+id               = "test-id"
+version          = "10.0.1"
+uri              = "test-uri-1"
+sha256           = "test-sha256-1"
+deprecation_date = "2026-12-31T00:00:00Z"
+
+[[metadata.dependencies]]
+id       = "test-id"
+version  = "10.1.2"
+uri      = "test-uri-2"
+checksum = "sha256:test-sha256-2"
+eol-date = "2027-12-31T00:00:00Z"
+
+[[metadata.dependencies]]
+id      = "other-id"
+version = "10.0.1"
+uri     = "test-uri-3"
+sha256  = "test-sha256-3"
+`))
+	})
+
+	it("fails when the eol date cannot be fetched", func() {
+		Expect(os.WriteFile(path, []byte(`[[metadata.dependencies]]
+id      = "test-id"
+version = "11.0.0"
+`), 0600)).To(Succeed())
+
+		b := carton.BuildModuleEOL{
+			BuildModulePath: path,
+			ID:              "test-id",
+			VersionPattern:  `.*`,
+			EolID:           "foo",
+		}
+
+		b.Refresh(carton.WithExitHandler(exitHandler))
+
+		exitHandler.AssertCalled(t, "Error", mock.Anything)
+	})
+}
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package carton
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// BuildModuleList reads the dependencies of a build module without modifying it.
+type BuildModuleList struct {
+	BuildModulePath string
+}
+
+// DependencyListing summarizes one dependency of a build module.
+type DependencyListing struct {
+	ID       string   `json:"id"`
+	Version  string   `json:"version"`
+	Arch     string   `json:"arch"`
+	PURLs    []string `json:"purls"`
+	CPECount int      `json:"cpeCount"`
+	EOLDate  string   `json:"eolDate,omitempty"`
+}
+
+// List returns a summary of each dependency, in the order they appear in the build module
+func (b BuildModuleList) List() ([]DependencyListing, error) {
+	md := make(map[string]interface{})
+	if _, err := toml.DecodeFile(b.BuildModulePath, &md); err != nil {
+		return nil, fmt.Errorf("unable to decode md %s\n%w", b.BuildModulePath, err)
+	}
+
+	dependencies, err := buildModuleDependencies(md, "")
+	if err != nil {
+		return nil, err
+	}
+
+	listings := []DependencyListing{}
+	for _, dep := range dependencies {
+		listing := DependencyListing{PURLs: dependencyPURLs(dep)}
+		listing.ID, _ = dep["id"].(string)
+		listing.Version, _ = dep["version"].(string)
+
+		// the arch is read from the first purl, whichever shape the purls are in
+		purl := map[string]interface{}{}
+		if len(listing.PURLs) > 0 {
+			purl["purl"] = listing.PURLs[0]
+		}
+		listing.Arch = dependencyArch(purl, func(arch string) string { return arch })
+
+		if cpes, ok := dep["cpes"].([]interface{}); ok {
+			listing.CPECount = len(cpes)
+		}
+
+		for _, key := range []string{"eol-date", "deprecation_date"} {
+			if date := formatDate(dep[key]); date != "" {
+				listing.EOLDate = date
+				break
+			}
+		}
+
+		listings = append(listings, listing)
+	}
+
+	return listings, nil
+}
+
+// dependencyPURLs returns the purls of a dependency, from either a `purl` string or a `purls` list
+func dependencyPURLs(dep map[string]interface{}) []string {
+	purls := []string{}
+
+	if purl, ok := dep["purl"].(string); ok {
+		purls = append(purls, purl)
+	}
+
+	if list, ok := dep["purls"].([]interface{}); ok {
+		for _, p := range list {
+			if purl, ok := p.(string); ok {
+				purls = append(purls, purl)
+			}
+		}
+	}
+
+	return purls
+}
+
+// formatDate formats a TOML date, which may be decoded as a string or a time.Time
+func formatDate(v interface{}) string {
+	switch d := v.(type) {
+	case string:
+		return d
+	case time.Time:
+		return d.Format(time.RFC3339)
+	default:
+		return ""
+	}
+}
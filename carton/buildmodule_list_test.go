@@ -0,0 +1,98 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package carton_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libpak-tools/carton"
+)
+
+func testBuildModuleList(t *testing.T, _ spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		path string
+	)
+
+	it.Before(func() {
+		path = filepath.Join(t.TempDir(), "buildpack.toml")
+		Expect(os.WriteFile(path, []byte(`api = "0.7"
+[[metadata.dependencies]]
+id               = "test-id"
+version          = "1.0.0"
+uri              = "test-uri-1"
+sha256           = "test-sha256-1"
+purl             = "pkg:generic/test@1.0.0?arch=arm64"
+cpes             = ["cpe:2.3:a:test:test:1.0.0:*:*:*:*:*:*:*", "cpe:2.3:a:other:test:1.0.0:*:*:*:*:*:*:*"]
+deprecation_date = 2030-01-01T00:00:00Z
+
+[[metadata.dependencies]]
+id       = "other-id"
+version  = "2.0.0"
+uri      = "test-uri-2"
+sha256   = "test-sha256-2"
+purls    = ["pkg:generic/other@2.0.0", "pkg:generic/other-alias@2.0.0"]
+eol-date = "2031-06-30"
+`), 0600)).To(Succeed())
+	})
+
+	it("lists each dependency", func() {
+		listings, err := carton.BuildModuleList{BuildModulePath: path}.List()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(listings).To(Equal([]carton.DependencyListing{
+			{
+				ID:       "test-id",
+				Version:  "1.0.0",
+				Arch:     "arm64",
+				PURLs:    []string{"pkg:generic/test@1.0.0?arch=arm64"},
+				CPECount: 2,
+				EOLDate:  "2030-01-01T00:00:00Z",
+			},
+			{
+				ID:      "other-id",
+				Version: "2.0.0",
+				Arch:    "amd64",
+				PURLs:   []string{"pkg:generic/other@2.0.0", "pkg:generic/other-alias@2.0.0"},
+				EOLDate: "2031-06-30",
+			},
+		}))
+	})
+
+	it("does not modify the build module", func() {
+		before, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = carton.BuildModuleList{BuildModulePath: path}.List()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.ReadFile(path)).To(Equal(before))
+	})
+
+	it("fails if the build module cannot be decoded", func() {
+		Expect(os.WriteFile(path, []byte("not [valid toml"), 0600)).To(Succeed())
+
+		_, err := carton.BuildModuleList{BuildModulePath: path}.List()
+		Expect(err).To(MatchError(ContainSubstring("unable to decode md")))
+	})
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package carton
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+
+	"github.com/paketo-buildpacks/libpak/v2/log"
+	"github.com/paketo-buildpacks/libpak/v2/utils"
+
+	"github.com/paketo-buildpacks/libpak-tools/internal"
+)
+
+// BuildModuleRemoval removes dependencies from a build module, e.g. when they reach their end of life. Dependencies
+// are selected the same way BuildModuleDependency selects the dependencies it updates.
+type BuildModuleRemoval struct {
+	BuildModulePath string
+	ID              string
+	VersionPattern  string
+
+	// Arch limits the removal to dependencies of this arch, dependencies of all arches are removed when empty
+	Arch string
+
+	// ExactVersion treats VersionPattern as a literal version that must match the whole version of a dependency,
+	// rather than as a regular expression
+	ExactVersion bool
+
+	// VersionConstraint is a SemVer range that selects the dependencies to remove instead of VersionPattern
+	VersionConstraint string
+
+	// NormalizeArch maps common alternate arch spellings, like x86_64 and aarch64, to their CNB names before matching
+	NormalizeArch bool
+
+	// MetadataSubkey selects dependencies grouped in a `[[metadata.dependencies.<subkey>]]` subsection instead of the
+	// flat `[[metadata.dependencies]]` array
+	MetadataSubkey string
+
+	// RequireMatch fails the removal when no dependency matches the id, arch and version pattern
+	RequireMatch bool
+
+	// TOMLIndent is the number of spaces nested tables are indented with, when zero the encoder default is used
+	TOMLIndent int
+
+	// Backup copies the build module to <path>.bak before it is rewritten, the copy is kept if the write fails
+	Backup bool
+}
+
+func (r BuildModuleRemoval) Remove(options ...Option) {
+	config := Config{
+		exitHandler: utils.NewExitHandler(),
+		progress:    os.Stderr,
+	}
+
+	for _, option := range options {
+		config = option(config)
+	}
+
+	logger := log.NewPaketoLogger(config.progress)
+	_, _ = fmt.Fprintf(logger.TitleWriter(), "\n%s\n", log.FormatIdentity(r.ID, cmp.Or(r.VersionConstraint, r.VersionPattern)))
+
+	b := BuildModuleDependency{
+		ID:                r.ID,
+		Arch:              r.Arch,
+		VersionPattern:    r.VersionPattern,
+		ExactVersion:      r.ExactVersion,
+		VersionConstraint: r.VersionConstraint,
+		NormalizeArch:     r.NormalizeArch,
+	}
+
+	versionExp, constraint, err := b.versionMatchers()
+	if err != nil {
+		config.exitHandler.Error(err)
+		return
+	}
+
+	tomlOptions := internal.TOMLOptions{Indent: r.TOMLIndent, Backup: r.Backup}
+	if err := internal.UpdateTOMLFile(r.BuildModulePath, tomlOptions, func(md map[string]interface{}) error {
+		dependencies, err := buildModuleDependencies(md, r.MetadataSubkey)
+		if err != nil {
+			return err
+		}
+
+		var kept []map[string]interface{}
+		for _, dep := range dependencies {
+			if depID, _ := dep["id"].(string); depID != r.ID {
+				kept = append(kept, dep)
+				continue
+			}
+
+			arch := dependencyArch(dep, b.normalizeArch)
+			if r.Arch != "" && arch != b.normalizeArch(r.Arch) {
+				kept = append(kept, dep)
+				continue
+			}
+
+			depVersion, ok := b.selectsVersion(dep, versionExp, constraint, logger)
+			if !ok {
+				kept = append(kept, dep)
+				continue
+			}
+
+			logger.Headerf("Removed:      %s %s (%s)", r.ID, depVersion, arch)
+		}
+
+		removed := len(dependencies) - len(kept)
+		if removed == 0 && r.RequireMatch {
+			return fmt.Errorf("no %s dependency matched arch %s and %s", r.ID, r.arch(), b.selection())
+		}
+		logger.Headerf("Removed %d of %d dependencies", removed, len(dependencies))
+
+		if kept == nil {
+			kept = []map[string]interface{}{}
+		}
+		return setBuildModuleDependencies(md, r.MetadataSubkey, kept)
+	}); err != nil {
+		config.exitHandler.Error(err)
+		return
+	}
+}
+
+// arch describes the arches that are removed, for error messages
+func (r BuildModuleRemoval) arch() string {
+	if r.Arch == "" {
+		return "any"
+	}
+
+	return r.Arch
+}
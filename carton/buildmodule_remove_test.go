@@ -0,0 +1,146 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package carton_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildpacks/libcnb/v2/mocks"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/stretchr/testify/mock"
+
+	libpakTesting "github.com/paketo-buildpacks/libpak/v2/testing"
+
+	"github.com/paketo-buildpacks/libpak-tools/carton"
+)
+
+func testBuildModuleRemoval(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		exitHandler *mocks.ExitHandler
+		path        string
+	)
+
+	it.Before(func() {
+		exitHandler = &mocks.ExitHandler{}
+		exitHandler.On("Error", mock.Anything)
+
+		path = filepath.Join(t.TempDir(), "buildpack.toml")
+		Expect(os.WriteFile(path, []byte(`# Copyright header
+
+api = "0.7"
+
+[[metadata.dependencies]]
+id      = "test-id"
+version = "1.0.0"
+purl    = "pkg:generic/test@1.0.0?arch=amd64"
+
+[[metadata.dependencies]]
+id      = "test-id"
+version = "1.0.0"
+purl    = "pkg:generic/test@1.0.0?arch=arm64"
+
+[[metadata.dependencies]]
+id      = "test-id"
+version = "2.0.0"
+purl    = "pkg:generic/test@2.0.0?arch=amd64"
+
+[[metadata.dependencies]]
+id      = "other-id"
+version = "1.0.0"
+`), 0600)).To(Succeed())
+	})
+
+	it("removes matching dependencies of all arches", func() {
+		progress := &bytes.Buffer{}
+
+		carton.BuildModuleRemoval{
+			BuildModulePath: path,
+			ID:              "test-id",
+			VersionPattern:  `^1\.0\.0$`,
+		}.Remove(carton.WithExitHandler(exitHandler), carton.WithProgressWriter(progress))
+
+		Expect(exitHandler.Calls).To(BeEmpty())
+		Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`api = "0.7"
+
+[[metadata.dependencies]]
+id      = "test-id"
+version = "2.0.0"
+purl    = "pkg:generic/test@2.0.0?arch=amd64"
+
+[[metadata.dependencies]]
+id      = "other-id"
+version = "1.0.0"
+`))
+		Expect(progress.String()).To(ContainSubstring("Removed 2 of 4 dependencies"))
+	})
+
+	it("only removes dependencies of the given arch", func() {
+		carton.BuildModuleRemoval{
+			BuildModulePath: path,
+			ID:              "test-id",
+			Arch:            "arm64",
+			VersionPattern:  `^1\.0\.0$`,
+		}.Remove(carton.WithExitHandler(exitHandler), carton.WithProgressWriter(&bytes.Buffer{}))
+
+		Expect(exitHandler.Calls).To(BeEmpty())
+		Expect(os.ReadFile(path)).NotTo(ContainSubstring("arch=arm64"))
+		Expect(os.ReadFile(path)).To(ContainSubstring("pkg:generic/test@1.0.0?arch=amd64"))
+	})
+
+	it("preserves leading comments", func() {
+		carton.BuildModuleRemoval{
+			BuildModulePath: path,
+			ID:              "test-id",
+			VersionPattern:  `^2\.0\.0$`,
+		}.Remove(carton.WithExitHandler(exitHandler), carton.WithProgressWriter(&bytes.Buffer{}))
+
+		Expect(exitHandler.Calls).To(BeEmpty())
+		Expect(os.ReadFile(path)).To(HavePrefix("# Copyright header\n"))
+	})
+
+	context("require match", func() {
+		it("fails when no dependency matches", func() {
+			carton.BuildModuleRemoval{
+				BuildModulePath: path,
+				ID:              "test-id",
+				VersionPattern:  `^3\.0\.0$`,
+				RequireMatch:    true,
+			}.Remove(carton.WithExitHandler(exitHandler), carton.WithProgressWriter(&bytes.Buffer{}))
+
+			Expect(exitHandler.Calls[0].Arguments.Get(0)).To(MatchError(
+				fmt.Sprintf("no test-id dependency matched arch any and version pattern %s", `^3\.0\.0$`)))
+			Expect(os.ReadFile(path)).To(ContainSubstring(`version = "2.0.0"`))
+		})
+
+		it("succeeds when no dependency matches without require match", func() {
+			carton.BuildModuleRemoval{
+				BuildModulePath: path,
+				ID:              "test-id",
+				VersionPattern:  `^3\.0\.0$`,
+			}.Remove(carton.WithExitHandler(exitHandler), carton.WithProgressWriter(&bytes.Buffer{}))
+
+			Expect(exitHandler.Calls).To(BeEmpty())
+		})
+	})
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package carton
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/paketo-buildpacks/libpak/v2/log"
+	"github.com/paketo-buildpacks/libpak/v2/utils"
+
+	"github.com/paketo-buildpacks/libpak-tools/internal"
+)
+
+// BuildModuleTargets sets the `targets` and `stacks` of build module dependencies to the same values.
+type BuildModuleTargets struct {
+	BuildModulePath string
+
+	// ID limits the update to dependencies with this id, all dependencies are updated when empty
+	ID string
+
+	// Targets replaces the `targets` of each dependency, when set
+	Targets []string
+
+	// Stacks replaces the `stacks` of each dependency, when set
+	Stacks []string
+
+	// DryRun logs the changes without writing them
+	DryRun bool
+
+	// TOMLIndent is the number of spaces nested tables are indented with, when zero the encoder default is used
+	TOMLIndent int
+
+	// FailOnNoChange fails the update when the build module is byte-identical after it is applied
+	FailOnNoChange bool
+}
+
+func (b BuildModuleTargets) Update(options ...Option) {
+	config := Config{
+		exitHandler: utils.NewExitHandler(),
+		progress:    os.Stderr,
+	}
+
+	for _, option := range options {
+		config = option(config)
+	}
+
+	logger := log.NewPaketoLogger(config.progress)
+	_, _ = fmt.Fprintf(logger.TitleWriter(), "\n%s\n", log.FormatIdentity(b.BuildModulePath, ""))
+	if b.DryRun {
+		logger.Header("Dry run, no changes will be written")
+	}
+
+	tomlOptions := internal.TOMLOptions{Indent: b.TOMLIndent, DryRun: b.DryRun, FailOnNoChange: b.FailOnNoChange}
+	if err := internal.UpdateTOMLFile(b.BuildModulePath, tomlOptions, func(md map[string]interface{}) error {
+		dependencies, err := buildModuleDependencies(md, "")
+		if err != nil {
+			return err
+		}
+
+		for _, dep := range dependencies {
+			depID, _ := dep["id"].(string)
+			if b.ID != "" && depID != b.ID {
+				continue
+			}
+
+			depVersion, _ := dep["version"].(string)
+			for _, key := range []struct {
+				name   string
+				values []string
+			}{{"targets", b.Targets}, {"stacks", b.Stacks}} {
+				if len(key.values) == 0 {
+					continue
+				}
+
+				old, found := dep[key.name]
+				if !found {
+					old = "none"
+				}
+
+				logger.Headerf("%s %s: %s %v -> %v", depID, depVersion, key.name, old, key.values)
+				dep[key.name] = key.values
+			}
+		}
+
+		return nil
+	}); err != nil {
+		config.exitHandler.Error(err)
+		return
+	}
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package carton_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildpacks/libcnb/v2/mocks"
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+	"github.com/stretchr/testify/mock"
+
+	libpakTesting "github.com/paketo-buildpacks/libpak/v2/testing"
+
+	"github.com/paketo-buildpacks/libpak-tools/carton"
+)
+
+func testBuildModuleTargets(t *testing.T, _ spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		exitHandler *mocks.ExitHandler
+		path        string
+	)
+
+	it.Before(func() {
+		exitHandler = &mocks.ExitHandler{}
+		exitHandler.On("Error", mock.Anything)
+
+		path = filepath.Join(t.TempDir(), "buildpack.toml")
+		Expect(os.WriteFile(path, []byte(`api = "0.7"
+[[metadata.dependencies]]
+id      = "test-id"
+version = "1.0.0"
+stacks  = ["io.buildpacks.stacks.bionic"]
+
+[[metadata.dependencies]]
+id      = "other-id"
+version = "2.0.0"
+targets = ["linux/amd64"]
+`), 0600)).To(Succeed())
+	})
+
+	it("sets targets on all dependencies", func() {
+		b := carton.BuildModuleTargets{
+			BuildModulePath: path,
+			Targets:         []string{"linux/amd64", "linux/arm64"},
+		}
+
+		b.Update(carton.WithExitHandler(exitHandler))
+
+		Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`api = "0.7"
+[[metadata.dependencies]]
+id      = "test-id"
+version = "1.0.0"
+stacks  = ["io.buildpacks.stacks.bionic"]
+targets = ["linux/amd64", "linux/arm64"]
+
+[[metadata.dependencies]]
+id      = "other-id"
+version = "2.0.0"
+targets = ["linux/amd64", "linux/arm64"]
+`))
+	})
+
+	it("sets stacks on dependencies with an id", func() {
+		b := carton.BuildModuleTargets{
+			BuildModulePath: path,
+			ID:              "test-id",
+			Stacks:          []string{"io.buildpacks.stacks.jammy", "*"},
+		}
+
+		b.Update(carton.WithExitHandler(exitHandler))
+
+		Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`api = "0.7"
+[[metadata.dependencies]]
+id      = "test-id"
+version = "1.0.0"
+stacks  = ["io.buildpacks.stacks.jammy", "*"]
+
+[[metadata.dependencies]]
+id      = "other-id"
+version = "2.0.0"
+targets = ["linux/amd64"]
+`))
+	})
+
+	it("does not write changes in a dry run", func() {
+		before, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		progress := &bytes.Buffer{}
+
+		b := carton.BuildModuleTargets{
+			BuildModulePath: path,
+			Targets:         []string{"linux/arm64"},
+			DryRun:          true,
+		}
+
+		b.Update(carton.WithExitHandler(exitHandler), carton.WithProgressWriter(progress))
+
+		Expect(os.ReadFile(path)).To(Equal(before))
+		Expect(progress.String()).To(ContainSubstring("other-id 2.0.0: targets [linux/amd64] -> [linux/arm64]"))
+	})
+}
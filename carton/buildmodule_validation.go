@@ -0,0 +1,134 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package carton
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/paketo-buildpacks/libpak-tools/internal"
+)
+
+// BuildModuleValidation checks the dependencies of a build module without modifying it.
+type BuildModuleValidation struct {
+	BuildModulePath string
+
+	// SkipURICheck skips checking that dependency URIs are reachable, for offline runs
+	SkipURICheck bool
+}
+
+// DependencyValidation is the outcome of validating one dependency, it is valid when there are no problems.
+type DependencyValidation struct {
+	ID       string
+	Version  string
+	Problems []string
+}
+
+func (d DependencyValidation) Valid() bool {
+	return len(d.Problems) == 0
+}
+
+// Validate checks that each dependency has a reachable URI, a parseable purl, valid CPEs and a checksum
+func (b BuildModuleValidation) Validate() ([]DependencyValidation, error) {
+	md := make(map[string]interface{})
+	if _, err := toml.DecodeFile(b.BuildModulePath, &md); err != nil {
+		return nil, fmt.Errorf("unable to decode md %s\n%w", b.BuildModulePath, err)
+	}
+
+	dependencies, err := buildModuleDependencies(md, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var results []DependencyValidation
+	for _, dep := range dependencies {
+		result := DependencyValidation{}
+		result.ID, _ = dep["id"].(string)
+		result.Version, _ = dep["version"].(string)
+
+		if uri, ok := dep["uri"].(string); !ok || uri == "" {
+			result.Problems = append(result.Problems, "uri is missing")
+		} else if !b.SkipURICheck {
+			if _, err := internal.CheckURI(uri); err != nil {
+				result.Problems = append(result.Problems, strings.ReplaceAll(err.Error(), "\n", " "))
+			}
+		}
+
+		_, hasPURL := dep["purl"]
+		_, hasPURLs := dep["purls"]
+		if !hasPURL && !hasPURLs {
+			result.Problems = append(result.Problems, "purl is missing")
+		}
+		for _, purl := range dependencyPURLs(dep) {
+			if !isValidPURL(purl) {
+				result.Problems = append(result.Problems, fmt.Sprintf("purl %q is not valid", purl))
+			}
+		}
+
+		if cpesUnwrapped, found := dep["cpes"]; found {
+			cpes, ok := cpesUnwrapped.([]interface{})
+			if !ok {
+				result.Problems = append(result.Problems, "cpes is not a list")
+			}
+
+			for _, c := range cpes {
+				if cpe, ok := c.(string); !ok || !IsValidCPE(cpe) {
+					result.Problems = append(result.Problems, fmt.Sprintf("cpe %v is not valid", c))
+				}
+			}
+		}
+
+		if !hasChecksum(dep) {
+			result.Problems = append(result.Problems, "checksum is missing")
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// IsValidCPE returns true if cpe is a CPE 2.3 formatted string or a CPE 2.2 URI
+func IsValidCPE(cpe string) bool {
+	if strings.HasPrefix(cpe, "cpe:/") {
+		return len(cpe) > len("cpe:/")
+	}
+
+	parts := strings.Split(strings.ReplaceAll(cpe, `\:`, ""), ":")
+	if len(parts) != 13 || parts[0] != "cpe" || parts[1] != "2.3" {
+		return false
+	}
+
+	switch parts[2] {
+	case "a", "o", "h", "*", "-":
+		return true
+	default:
+		return false
+	}
+}
+
+func hasChecksum(dep map[string]interface{}) bool {
+	for _, key := range []string{"checksum", "sha256"} {
+		if checksum, ok := dep[key].(string); ok && checksum != "" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package carton_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libpak-tools/carton"
+)
+
+func testBuildModuleValidation(t *testing.T, _ spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		path   string
+		server *httptest.Server
+	)
+
+	it.Before(func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {})
+		server = httptest.NewServer(mux)
+
+		path = filepath.Join(t.TempDir(), "buildpack.toml")
+		Expect(os.WriteFile(path, []byte(fmt.Sprintf(`api = "0.7"
+[[metadata.dependencies]]
+id      = "valid"
+version = "1.0.0"
+uri     = "%[1]s/ok"
+sha256  = "test-sha256"
+purl    = "pkg:generic/valid@1.0.0?arch=amd64"
+cpes    = ["cpe:2.3:a:test:valid:1.0.0:*:*:*:*:*:*:*"]
+
+[[metadata.dependencies]]
+id       = "invalid"
+version  = "2.0.0"
+uri      = "%[1]s/missing"
+purl     = "generic/invalid"
+cpes     = ["cpe:2.3:a:test"]
+`, server.URL)), 0600)).To(Succeed())
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	it("reports the problems of each dependency", func() {
+		results, err := carton.BuildModuleValidation{BuildModulePath: path}.Validate()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(results).To(HaveLen(2))
+		Expect(results[0].ID).To(Equal("valid"))
+		Expect(results[0].Valid()).To(BeTrue())
+
+		Expect(results[1].ID).To(Equal("invalid"))
+		Expect(results[1].Version).To(Equal("2.0.0"))
+		Expect(results[1].Problems).To(ConsistOf(
+			ContainSubstring("status: 404"),
+			`purl "generic/invalid" is not valid`,
+			"cpe cpe:2.3:a:test is not valid",
+			"checksum is missing",
+		))
+	})
+
+	it("skips the uri check", func() {
+		results, err := carton.BuildModuleValidation{BuildModulePath: path, SkipURICheck: true}.Validate()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(results[1].Problems).To(HaveLen(3))
+		Expect(results[1].Problems).NotTo(ContainElement(ContainSubstring("status: 404")))
+	})
+
+	it("does not modify the build module", func() {
+		before, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = carton.BuildModuleValidation{BuildModulePath: path, SkipURICheck: true}.Validate()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.ReadFile(path)).To(Equal(before))
+	})
+
+	it("validates each purl of a purls list", func() {
+		Expect(os.WriteFile(path, []byte(`api = "0.7"
+[[metadata.dependencies]]
+id      = "purls"
+version = "1.0.0"
+uri     = "https://localhost/purls"
+sha256  = "test-sha256"
+purls   = ["pkg:generic/purls@1.0.0?arch=amd64", "generic/invalid", "pkg:generic/purls@1.0.0?arch=amd64&arch=arm64"]
+
+[[metadata.dependencies]]
+id      = "missing"
+version = "1.0.0"
+uri     = "https://localhost/missing"
+sha256  = "test-sha256"
+`), 0600)).To(Succeed())
+
+		results, err := carton.BuildModuleValidation{BuildModulePath: path, SkipURICheck: true}.Validate()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(results[0].Problems).To(ConsistOf(
+			`purl "generic/invalid" is not valid`,
+			`purl "pkg:generic/purls@1.0.0?arch=amd64&arch=arm64" is not valid`,
+		))
+		Expect(results[1].Problems).To(ConsistOf("purl is missing"))
+	})
+
+	it("validates cpes", func() {
+		Expect(carton.IsValidCPE("cpe:2.3:a:apache:tomcat:10.1.0:*:*:*:*:*:*:*")).To(BeTrue())
+		Expect(carton.IsValidCPE(`cpe:2.3:a:test:some\:thing:1.0:*:*:*:*:*:*:*`)).To(BeTrue())
+		Expect(carton.IsValidCPE("cpe:/a:apache:tomcat:10.1.0")).To(BeTrue())
+		Expect(carton.IsValidCPE("cpe:2.3:x:apache:tomcat:10.1.0:*:*:*:*:*:*:*")).To(BeFalse())
+		Expect(carton.IsValidCPE("tomcat")).To(BeFalse())
+	})
+}
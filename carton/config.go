@@ -17,6 +17,8 @@
 package carton
 
 import (
+	"io"
+
 	"github.com/buildpacks/libcnb/v2"
 
 	"github.com/paketo-buildpacks/libpak/v2/effect"
@@ -36,6 +38,7 @@ type Config struct {
 	entryWriter EntryWriter
 	executor    effect.Executor
 	exitHandler libcnb.ExitHandler
+	progress    io.Writer
 }
 
 // Option is a function for configuring a Config instance.
@@ -64,3 +67,12 @@ func WithExitHandler(exitHandler libcnb.ExitHandler) Option {
 		return config
 	}
 }
+
+// WithProgressWriter creates an Option that sets where progress is logged, by default it is os.Stderr so that stdout
+// is kept for command output.
+func WithProgressWriter(progress io.Writer) Option {
+	return func(config Config) Config {
+		config.progress = progress
+		return config
+	}
+}
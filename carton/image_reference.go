@@ -0,0 +1,81 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package carton
+
+import (
+	"strings"
+)
+
+// ImageReference is an image reference split into its parts, e.g. `docker://registry:5000/org/img:1.2.3@sha256:...`
+type ImageReference struct {
+
+	// Scheme is the optional URI scheme including the separator, e.g. `docker://`.
+	Scheme string
+
+	// Repository is the registry (including any port) and path of the image.
+	Repository string
+
+	// Tag is the optional tag of the image.
+	Tag string
+
+	// Digest is the optional digest of the image, e.g. `sha256:...`.
+	Digest string
+}
+
+// ParseImageReference splits an image reference into scheme, repository, tag and digest. A `:` is only treated as
+// a tag separator when it appears after the last `/`, so registry ports are preserved.
+func ParseImageReference(ref string) ImageReference {
+	var r ImageReference
+
+	if i := strings.Index(ref, "://"); i >= 0 {
+		r.Scheme = ref[:i+3]
+		ref = ref[i+3:]
+	}
+
+	if name, digest, found := strings.Cut(ref, "@"); found {
+		ref = name
+		r.Digest = digest
+	}
+
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		r.Tag = ref[i+1:]
+		ref = ref[:i]
+	}
+
+	r.Repository = ref
+	return r
+}
+
+// Version returns the tag of the reference, or its digest if it is not tagged.
+func (r ImageReference) Version() string {
+	if r.Tag != "" {
+		return r.Tag
+	}
+
+	return r.Digest
+}
+
+func (r ImageReference) String() string {
+	s := r.Scheme + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package carton_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libpak-tools/carton"
+)
+
+func testImageReference(t *testing.T, _ spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+	)
+
+	it("parses a tagged reference", func() {
+		r := carton.ParseImageReference("docker://gcr.io/paketo-buildpacks/java:1.2.3")
+		Expect(r).To(Equal(carton.ImageReference{Scheme: "docker://", Repository: "gcr.io/paketo-buildpacks/java", Tag: "1.2.3"}))
+		Expect(r.String()).To(Equal("docker://gcr.io/paketo-buildpacks/java:1.2.3"))
+	})
+
+	it("parses a reference with a registry port", func() {
+		r := carton.ParseImageReference("registry:5000/paketo-buildpacks/java:1.2.3")
+		Expect(r).To(Equal(carton.ImageReference{Repository: "registry:5000/paketo-buildpacks/java", Tag: "1.2.3"}))
+	})
+
+	it("parses an untagged reference with a registry port", func() {
+		r := carton.ParseImageReference("registry:5000/paketo-buildpacks/java")
+		Expect(r).To(Equal(carton.ImageReference{Repository: "registry:5000/paketo-buildpacks/java"}))
+	})
+
+	it("parses a digest pinned reference", func() {
+		r := carton.ParseImageReference("docker://gcr.io/paketo-buildpacks/java:1.2.3@sha256:abc")
+		Expect(r).To(Equal(carton.ImageReference{Scheme: "docker://", Repository: "gcr.io/paketo-buildpacks/java", Tag: "1.2.3", Digest: "sha256:abc"}))
+		Expect(r.Version()).To(Equal("1.2.3"))
+		Expect(r.String()).To(Equal("docker://gcr.io/paketo-buildpacks/java:1.2.3@sha256:abc"))
+
+		r = carton.ParseImageReference("gcr.io/paketo-buildpacks/java@sha256:abc")
+		Expect(r.Version()).To(Equal("sha256:abc"))
+	})
+}
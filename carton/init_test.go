@@ -26,7 +26,16 @@ import (
 func TestUnit(t *testing.T) {
 	suite := spec.New("libpak/carton", spec.Report(report.Terminal{}))
 	suite("BuildpackDependency", testBuildpackDependency)
+	suite("BuildModuleBatch", testBuildModuleBatch)
+	suite("BuildModuleDiff", testBuildModuleDiff)
+	suite("BuildModuleEOL", testBuildModuleEOL)
+	suite("BuildModuleList", testBuildModuleList)
+	suite("BuildModuleRemoval", testBuildModuleRemoval)
+	suite("BuildModuleTargets", testBuildModuleTargets)
+	suite("BuildModuleValidation", testBuildModuleValidation)
 	suite("BuildImageDependency", testBuildImageDependency)
+	suite("BuilderDiff", testBuilderDiff)
+	suite("ImageReference", testImageReference)
 	suite("LifecycleDependency", testLifecycleDependency)
 	suite("Netrc", testNetrc)
 	suite("Package", testPackage)
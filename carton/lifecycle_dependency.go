@@ -38,13 +38,14 @@ type LifecycleDependency struct {
 func (l LifecycleDependency) Update(options ...Option) {
 	config := Config{
 		exitHandler: utils.NewExitHandler(),
+		progress:    os.Stderr,
 	}
 
 	for _, option := range options {
 		config = option(config)
 	}
 
-	logger := log.NewPaketoLogger(os.Stdout)
+	logger := log.NewPaketoLogger(config.progress)
 	_, _ = fmt.Fprintf(logger.TitleWriter(), "\n%s\n", log.FormatIdentity("Lifecycle", l.Version))
 
 	c, err := os.ReadFile(l.BuilderPath)
@@ -17,6 +17,7 @@
 package carton_test
 
 import (
+	"bytes"
 	"os"
 	"testing"
 
@@ -77,4 +78,38 @@ uri = "https://github.com/buildpacks/lifecycle/releases/download/vtest-version-3
 test-epilogue
 `)))
 	})
+
+	it("writes progress to the progress writer", func() {
+		progress := &bytes.Buffer{}
+
+		d := carton.LifecycleDependency{
+			BuilderPath: path,
+			Version:     "test-version-3",
+		}
+
+		d.Update(carton.WithExitHandler(exitHandler), carton.WithProgressWriter(progress))
+
+		Expect(progress.String()).To(ContainSubstring("test-version-3"))
+	})
+
+	it("writes progress to stderr and nothing to stdout by default", func() {
+		stdout, err := os.CreateTemp(t.TempDir(), "stdout")
+		Expect(err).NotTo(HaveOccurred())
+		stderr, err := os.CreateTemp(t.TempDir(), "stderr")
+		Expect(err).NotTo(HaveOccurred())
+
+		originalStdout, originalStderr := os.Stdout, os.Stderr
+		os.Stdout, os.Stderr = stdout, stderr
+		defer func() { os.Stdout, os.Stderr = originalStdout, originalStderr }()
+
+		d := carton.LifecycleDependency{
+			BuilderPath: path,
+			Version:     "test-version-3",
+		}
+
+		d.Update(carton.WithExitHandler(exitHandler))
+
+		Expect(os.ReadFile(stdout.Name())).To(BeEmpty())
+		Expect(os.ReadFile(stderr.Name())).To(ContainSubstring("test-version-3"))
+	})
 }
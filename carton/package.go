@@ -49,6 +49,10 @@ type Package struct {
 	// StrictDependencyFilters indicates that a filter must match both the ID and version, otherwise it must only match one of the two
 	StrictDependencyFilters bool
 
+	// ExcludeDependencyFilters are regular expressions, a dependency whose ID or Version matches any of them is
+	// excluded even if it matches DependencyFilters
+	ExcludeDependencyFilters []string
+
 	// IncludeDependencies indicates whether to include dependencies in build package.
 	IncludeDependencies bool
 
@@ -71,6 +75,7 @@ func (p Package) Create(options ...Option) {
 		entryWriter: utils.EntryWriter{},
 		executor:    effect.NewExecutor(),
 		exitHandler: utils.NewExitHandler(),
+		progress:    os.Stderr,
 	}
 
 	for _, option := range options {
@@ -82,7 +87,7 @@ func (p Package) Create(options ...Option) {
 		file string
 	)
 
-	logger := log.NewPaketoLogger(os.Stdout)
+	logger := log.NewPaketoLogger(config.progress)
 
 	// Is this a buildpack or an extension?
 	bpfile := filepath.Join(p.Source, "buildpack.toml")
@@ -252,12 +257,23 @@ func (p Package) Create(options ...Option) {
 			return
 		}
 
+		excludeFilters, err := compileFilters(p.ExcludeDependencyFilters)
+		if err != nil {
+			config.exitHandler.Error(err)
+			return
+		}
+
 		for _, dep := range metadata.Dependencies {
 			if !p.matchDependency(dep) {
 				logger.Bodyf("Skipping [%s or %s] which matched a filter", dep.ID, dep.Version)
 				continue
 			}
 
+			if excludeDependency(excludeFilters, dep) {
+				logger.Bodyf("Skipping [%s or %s] which matched an exclude filter", dep.ID, dep.Version)
+				continue
+			}
+
 			logger.Headerf("Caching %s", color.BlueString("%s %s", dep.Name, dep.Version))
 
 			f, err := cache.Artifact(dep, n.BasicAuth)
@@ -301,6 +317,21 @@ func (p Package) Create(options ...Option) {
 	}
 }
 
+// ResolveCacheLocation returns location, or dependencies in the working directory when it is empty, so that every
+// command caches dependencies in the same place by default
+func ResolveCacheLocation(location string) (string, error) {
+	if location != "" {
+		return location, nil
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("unable to get working directory\n%w", err)
+	}
+
+	return filepath.Join(wd, "dependencies"), nil
+}
+
 // matchDependency checks all filters against dependency and returns true if there is a match (or no filters) and false if there is no match
 // There is a match if a regular expression matches against the ID or Version
 func (p Package) matchDependency(dep libpak.BuildModuleDependency) bool {
@@ -319,3 +350,28 @@ func (p Package) matchDependency(dep libpak.BuildModuleDependency) bool {
 
 	return false
 }
+
+// compileFilters compiles each of the raw regular expression filters
+func compileFilters(rawFilters []string) ([]*regexp.Regexp, error) {
+	var filters []*regexp.Regexp
+	for _, rawFilter := range rawFilters {
+		filter, err := regexp.Compile(rawFilter)
+		if err != nil {
+			return nil, fmt.Errorf("unable to compile dependency filter %s\n%w", rawFilter, err)
+		}
+		filters = append(filters, filter)
+	}
+
+	return filters, nil
+}
+
+// excludeDependency returns true if any of the filters matches the ID or Version of the dependency
+func excludeDependency(filters []*regexp.Regexp, dep libpak.BuildModuleDependency) bool {
+	for _, filter := range filters {
+		if filter.MatchString(dep.ID) || filter.MatchString(dep.Version) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -17,15 +17,15 @@
 package carton
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 
-	"github.com/BurntSushi/toml"
-
 	"github.com/paketo-buildpacks/libpak/v2/log"
 	"github.com/paketo-buildpacks/libpak/v2/utils"
+
+	"github.com/paketo-buildpacks/libpak-tools/internal"
 )
 
 type PackageDependency struct {
@@ -34,157 +34,213 @@ type PackageDependency struct {
 	ID            string
 	Version       string
 	PackagePath   string
+	ExtensionPath string
+
+	// TOMLIndent is the number of spaces nested tables are indented with, when zero the encoder default is used
+	TOMLIndent int
+
+	// FailOnNoChange fails the update when none of the files are changed by it
+	FailOnNoChange bool
+
+	// URIPrefix is the scheme of the image references that are updated in builder.toml and package.toml, defaults to
+	// docker://. References without a scheme and urn:cnb:registry: references are always updated.
+	URIPrefix string
 }
 
 func (p PackageDependency) Update(options ...Option) {
 	config := Config{
 		exitHandler: utils.NewExitHandler(),
+		progress:    os.Stderr,
 	}
 
 	for _, option := range options {
 		config = option(config)
 	}
 
-	logger := log.NewPaketoLogger(os.Stdout)
+	logger := log.NewPaketoLogger(config.progress)
 	_, _ = fmt.Fprintf(logger.TitleWriter(), "\n%s\n", log.FormatIdentity(p.ID, p.Version))
 
-	if p.BuilderPath != "" {
-		if err := updateFile(p.BuilderPath, updateByKey("buildpacks", p.ID, p.Version)); err != nil {
-			config.exitHandler.Error(fmt.Errorf("unable to update %s\n%w", p.BuilderPath, err))
+	tomlOptions := internal.TOMLOptions{Indent: p.TOMLIndent, FailOnNoChange: p.FailOnNoChange}
+
+	// every file is rendered before any is written, so that a failure leaves all of them unchanged
+	var paths, unchanged []string
+	var updates []renderedFile
+	for _, r := range []struct {
+		path string
+		f    func(md map[string]interface{})
+	}{
+		{p.BuilderPath, updateByKey("buildpacks", p.ID, p.Version, p.uriPrefix())},
+		{p.PackagePath, updateByKey("dependencies", p.ID, p.Version, p.uriPrefix())},
+		// Do we have a buildpack.toml with an order element? (composite buildpack)
+		{p.BuildpackPath, updateOrder(p.ID, p.Version)},
+		// extension.toml order references are updated the same way
+		{p.ExtensionPath, updateOrder(p.ID, p.Version)},
+	} {
+		if r.path == "" {
+			continue
 		}
+		paths = append(paths, r.path)
+
+		b, err := renderFile(r.path, tomlOptions, r.f)
+		if errors.Is(err, internal.ErrNoChange) {
+			unchanged = append(unchanged, r.path)
+			continue
+		} else if err != nil {
+			config.exitHandler.Error(fmt.Errorf("unable to update %s, no files were changed\n%w", r.path, err))
+			return
+		}
+
+		updates = append(updates, renderedFile{path: r.path, content: b})
 	}
 
-	if p.PackagePath != "" {
-		if err := updateFile(p.PackagePath, updateByKey("dependencies", p.ID, p.Version)); err != nil {
-			config.exitHandler.Error(fmt.Errorf("unable to update %s\n%w", p.PackagePath, err))
+	if p.FailOnNoChange && len(unchanged) == len(paths) {
+		config.exitHandler.Error(fmt.Errorf("no changes were made to %s", strings.Join(unchanged, ", ")))
+		return
+	}
+
+	for _, u := range updates {
+		if err := internal.WriteTOMLFile(u.path, u.content, tomlOptions); err != nil {
+			config.exitHandler.Error(fmt.Errorf("unable to update %s\n%w", u.path, err))
+			return
 		}
 	}
+}
+
+// renderedFile is the new content of a file that is written once every file has been rendered
+type renderedFile struct {
+	path    string
+	content []byte
+}
 
-	// Do we have a buildpack.toml with an order element? (composite buildpack)
-	if p.BuildpackPath != "" {
-		if err := updateFile(p.BuildpackPath, func(md map[string]interface{}) {
-			parts := strings.Split(p.ID, "/")
-			id := strings.Join(parts[len(parts)-2:], "/")
+func updateOrder(fullID, version string) func(md map[string]interface{}) {
+	return func(md map[string]interface{}) {
+		parts := strings.Split(fullID, "/")
+		id := strings.Join(parts[len(parts)-2:], "/")
 
-			groupsUnwrapped, found := md["order"]
+		groupsUnwrapped, found := md["order"]
+		if !found {
+			return
+		}
+
+		groups, ok := internal.TableArray(groupsUnwrapped)
+		if !ok {
+			return
+		}
+
+		for _, group := range groups {
+			buildpacksUnwrapped, found := group["group"]
 			if !found {
-				return
+				continue
 			}
 
-			groups, ok := groupsUnwrapped.([]map[string]interface{})
+			buildpacks, ok := buildpacksUnwrapped.([]interface{})
 			if !ok {
-				return
+				continue
 			}
 
-			for _, group := range groups {
-				buildpacksUnwrapped, found := group["group"]
+			for _, bpw := range buildpacks {
+				bp, ok := bpw.(map[string]interface{})
+				if !ok {
+					continue
+				}
+
+				bpIDUnwrappd, found := bp["id"]
 				if !found {
 					continue
 				}
 
-				buildpacks, ok := buildpacksUnwrapped.([]interface{})
+				bpID, ok := bpIDUnwrappd.(string)
 				if !ok {
 					continue
 				}
 
-				for _, bpw := range buildpacks {
-					bp, ok := bpw.(map[string]interface{})
-					if !ok {
-						continue
-					}
-
-					bpIDUnwrappd, found := bp["id"]
-					if !found {
-						continue
-					}
-
-					bpID, ok := bpIDUnwrappd.(string)
-					if !ok {
-						continue
-					}
-
-					if bpID == id {
-						bp["version"] = p.Version
-					}
+				if bpID == id {
+					bp["version"] = version
 				}
 			}
-		}); err != nil {
-			config.exitHandler.Error(fmt.Errorf("unable to update %s\n%w", p.BuildpackPath, err))
 		}
 	}
 }
 
-func updateByKey(key, id, version string) func(md map[string]interface{}) {
+// uriPrefix returns the scheme of the image references that are updated
+func (p PackageDependency) uriPrefix() string {
+	if p.URIPrefix != "" {
+		return p.URIPrefix
+	}
+
+	return "docker://"
+}
+
+// cnbRegistryPrefix is the prefix of a buildpack registry reference, e.g. urn:cnb:registry:paketo-buildpacks/java@1.2.3
+const cnbRegistryPrefix = "urn:cnb:registry:"
+
+// updateByKey updates the entries of the key array of tables that reference the buildpack id. The tag of an image uri
+// with uriPrefix, or without a scheme, and the version of a urn:cnb:registry: uri are replaced by version. When an
+// entry also has a version key, that is set to version as well.
+func updateByKey(key, id, version, uriPrefix string) func(md map[string]interface{}) {
 	return func(md map[string]interface{}) {
 		valuesUnwrapped, found := md[key]
 		if !found {
 			return
 		}
 
-		values, ok := valuesUnwrapped.([]interface{})
+		values, ok := internal.TableArray(valuesUnwrapped)
 		if !ok {
 			return
 		}
 
-		for _, bpw := range values {
-			bp, ok := bpw.(map[string]interface{})
-			if !ok {
-				continue
-			}
+		parts := strings.Split(id, "/")
+		shortID := strings.Join(parts[max(len(parts)-2, 0):], "/")
 
-			uriUnwrapped, found := bp["uri"]
-			if !found {
-				continue
+		for _, bp := range values {
+			_, hasVersion := bp["version"]
+
+			matched := false
+			if uri, ok := bp["uri"].(string); ok {
+				if strings.HasPrefix(uri, cnbRegistryPrefix) {
+					registryID, _, found := strings.Cut(strings.TrimPrefix(uri, cnbRegistryPrefix), "@")
+					if registryID == id || registryID == shortID {
+						matched = true
+
+						// an entry with a version key may leave the version out of its uri
+						if !hasVersion || found {
+							bp["uri"] = fmt.Sprintf("%s%s@%s", cnbRegistryPrefix, registryID, version)
+						}
+					}
+				} else {
+					rest, hasPrefix := strings.CutPrefix(uri, uriPrefix)
+					ref := ParseImageReference(rest)
+					if ref.Scheme == "" && ref.Repository == id {
+						matched = true
+
+						// an entry with a version key may leave the tag out of its uri
+						if !hasVersion || ref.Tag != "" || ref.Digest != "" {
+							// a digest pins the previous image, so it is replaced by the new tag
+							ref.Tag = version
+							ref.Digest = ""
+							if hasPrefix {
+								ref.Scheme = uriPrefix
+							}
+							bp["uri"] = ref.String()
+						}
+					}
+				}
 			}
 
-			uri, ok := uriUnwrapped.(string)
-			if !ok {
-				continue
+			if bpID, ok := bp["id"].(string); ok && (bpID == id || bpID == shortID) {
+				matched = true
 			}
 
-			if strings.HasPrefix(uri, fmt.Sprintf("docker://%s", id)) {
-				parts := strings.Split(uri, ":")
-				bp["uri"] = fmt.Sprintf("%s:%s", strings.Join(parts[0:2], ":"), version)
+			if matched && hasVersion {
+				bp["version"] = version
 			}
 		}
 	}
 }
 
-func updateFile(cfgPath string, f func(md map[string]interface{})) error {
-	c, err := os.ReadFile(cfgPath)
-	if err != nil {
-		return fmt.Errorf("unable to read %s\n%w", cfgPath, err)
-	}
-
-	// save any leading comments, this is to preserve license headers
-	// inline comments will be lost
-	comments := []byte{}
-	for i, line := range bytes.SplitAfter(c, []byte("\n")) {
-		if bytes.HasPrefix(line, []byte("#")) || (i > 0 && len(bytes.TrimSpace(line)) == 0) {
-			comments = append(comments, line...)
-		} else {
-			break // stop on first comment
-		}
-	}
-
-	md := make(map[string]interface{})
-	if err := toml.Unmarshal(c, &md); err != nil {
-		return fmt.Errorf("unable to decode md %s\n%w", cfgPath, err)
-	}
-
-	f(md)
-
-	b, err := utils.Marshal(md)
-	if err != nil {
-		return fmt.Errorf("unable to encode md %s\n%w", cfgPath, err)
-	}
-
-	b = append(comments, b...)
-
-	// #nosec G306 - permissions need to be 644 on the package
-	if err := os.WriteFile(cfgPath, b, 0644); err != nil {
-		return fmt.Errorf("unable to write %s\n%w", cfgPath, err)
-	}
-
-	return nil
+func renderFile(cfgPath string, options internal.TOMLOptions, f func(md map[string]interface{})) ([]byte, error) {
+	return internal.RenderTOMLFile(cfgPath, options, func(md map[string]interface{}) error {
+		f(md)
+		return nil
+	})
 }
@@ -17,7 +17,10 @@
 package carton_test
 
 import (
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/buildpacks/libcnb/v2/mocks"
@@ -158,6 +161,41 @@ group = [
 			version="test-version-2"`))
 	})
 
+	it("updates extension order dependency", func() {
+		Expect(os.WriteFile(path, []byte(`api = "0.9"
+[extension]
+id = "some-id"
+name = "some-name"
+
+[[order]]
+group = [
+	{ id = "paketo-buildpacks/test-1", version="test-version-1" },
+	{ id = "paketo-buildpacks/test-2", version="test-version-2" },
+]`), 0600)).To(Succeed())
+
+		p := carton.PackageDependency{
+			ExtensionPath: path,
+			ID:            "gcr.io/paketo-buildpacks/test-1",
+			Version:       "test-version-3",
+		}
+
+		p.Update(carton.WithExitHandler(exitHandler))
+
+		Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`api = "0.9"
+[extension]
+id = "some-id"
+name = "some-name"
+
+[[order]]
+[[order.group]]
+	id = "paketo-buildpacks/test-1"
+	version="test-version-3"
+[[order.group]]
+	id = "paketo-buildpacks/test-2"
+	version="test-version-2"
+`))
+	})
+
 	it("updates builder dependency", func() {
 		Expect(os.WriteFile(path, []byte(`buildpacks = [
 	{ id = "paketo-buildpacks/test-1", uri = "docker://gcr.io/paketo-buildpacks/test-1:test-version-1" },
@@ -202,6 +240,175 @@ group = [
 		uri = "docker://gcr.io/paketo-buildpacks/test-2:test-version-2"`))
 	})
 
+	it("updates the version of a package dependency that has one", func() {
+		Expect(os.WriteFile(path, []byte(`[[dependencies]]
+  uri = "docker://gcr.io/paketo-buildpacks/test-1:test-version-1"
+  version = "test-version-1"
+
+[[dependencies]]
+  uri = "docker://gcr.io/paketo-buildpacks/test-2"
+  version = "test-version-2"
+
+[[dependencies]]
+  uri = "docker://gcr.io/paketo-buildpacks/test-3:test-version-1"
+`), 0600)).To(Succeed())
+
+		for _, id := range []string{"gcr.io/paketo-buildpacks/test-1", "gcr.io/paketo-buildpacks/test-2"} {
+			p := carton.PackageDependency{
+				PackagePath: path,
+				ID:          id,
+				Version:     "test-version-3",
+			}
+
+			p.Update(carton.WithExitHandler(exitHandler))
+		}
+
+		Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`[[dependencies]]
+  uri = "docker://gcr.io/paketo-buildpacks/test-1:test-version-3"
+  version = "test-version-3"
+
+[[dependencies]]
+  uri = "docker://gcr.io/paketo-buildpacks/test-2"
+  version = "test-version-3"
+
+[[dependencies]]
+  uri = "docker://gcr.io/paketo-buildpacks/test-3:test-version-1"
+`))
+	})
+
+	it("updates the version of a package dependency referenced by id", func() {
+		Expect(os.WriteFile(path, []byte(`[[dependencies]]
+  id = "paketo-buildpacks/test-1"
+  uri = "urn:cnb:registry:paketo-buildpacks/test-1"
+  version = "test-version-1"
+`), 0600)).To(Succeed())
+
+		p := carton.PackageDependency{
+			PackagePath: path,
+			ID:          "gcr.io/paketo-buildpacks/test-1",
+			Version:     "test-version-3",
+		}
+
+		p.Update(carton.WithExitHandler(exitHandler))
+
+		Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`[[dependencies]]
+  id = "paketo-buildpacks/test-1"
+  uri = "urn:cnb:registry:paketo-buildpacks/test-1"
+  version = "test-version-3"
+`))
+	})
+
+	it("updates urn:cnb:registry: package dependencies", func() {
+		Expect(os.WriteFile(path, []byte(`dependencies = [
+	{ uri = "urn:cnb:registry:paketo-buildpacks/test-1@test-version-1" },
+	{ uri = "urn:cnb:registry:paketo-buildpacks/test-10@test-version-1" },
+	{ uri = "urn:cnb:registry:paketo-buildpacks/test-1", version = "test-version-1" },
+]`), 0600)).To(Succeed())
+
+		p := carton.PackageDependency{
+			PackagePath: path,
+			ID:          "gcr.io/paketo-buildpacks/test-1",
+			Version:     "test-version-3",
+		}
+
+		p.Update(carton.WithExitHandler(exitHandler))
+
+		Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`[[dependencies]]
+		uri = "urn:cnb:registry:paketo-buildpacks/test-1@test-version-3"
+
+	  [[dependencies]]
+		uri = "urn:cnb:registry:paketo-buildpacks/test-10@test-version-1"
+
+	  [[dependencies]]
+		uri = "urn:cnb:registry:paketo-buildpacks/test-1"
+		version = "test-version-3"`))
+	})
+
+	it("updates package dependencies without a scheme", func() {
+		Expect(os.WriteFile(path, []byte(`dependencies = [
+	{ uri = "gcr.io/paketo-buildpacks/test-1:test-version-1" },
+	{ uri = "oci://gcr.io/paketo-buildpacks/test-1:test-version-1" },
+]`), 0600)).To(Succeed())
+
+		p := carton.PackageDependency{
+			PackagePath: path,
+			ID:          "gcr.io/paketo-buildpacks/test-1",
+			Version:     "test-version-3",
+		}
+
+		p.Update(carton.WithExitHandler(exitHandler))
+
+		Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`[[dependencies]]
+		uri = "gcr.io/paketo-buildpacks/test-1:test-version-3"
+
+	  [[dependencies]]
+		uri = "oci://gcr.io/paketo-buildpacks/test-1:test-version-1"`))
+	})
+
+	it("updates package dependencies with a uri prefix", func() {
+		Expect(os.WriteFile(path, []byte(`dependencies = [
+	{ uri = "oci://gcr.io/paketo-buildpacks/test-1:test-version-1" },
+	{ uri = "docker://gcr.io/paketo-buildpacks/test-1:test-version-1" },
+]`), 0600)).To(Succeed())
+
+		p := carton.PackageDependency{
+			PackagePath: path,
+			ID:          "gcr.io/paketo-buildpacks/test-1",
+			Version:     "test-version-3",
+			URIPrefix:   "oci://",
+		}
+
+		p.Update(carton.WithExitHandler(exitHandler))
+
+		Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`[[dependencies]]
+		uri = "oci://gcr.io/paketo-buildpacks/test-1:test-version-3"
+
+	  [[dependencies]]
+		uri = "docker://gcr.io/paketo-buildpacks/test-1:test-version-1"`))
+	})
+
+	it("updates package dependency on a registry with a port", func() {
+		Expect(os.WriteFile(path, []byte(`dependencies = [
+	{ uri = "docker://registry.example.com:5000/paketo-buildpacks/test-1:test-version-1" },
+	{ uri = "docker://registry.example.com:5000/paketo-buildpacks/test-10:test-version-1" },
+]`), 0600)).To(Succeed())
+
+		p := carton.PackageDependency{
+			PackagePath: path,
+			ID:          "registry.example.com:5000/paketo-buildpacks/test-1",
+			Version:     "test-version-3",
+		}
+
+		p.Update(carton.WithExitHandler(exitHandler))
+
+		Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`[[dependencies]]
+		uri = "docker://registry.example.com:5000/paketo-buildpacks/test-1:test-version-3"
+
+	  [[dependencies]]
+		uri = "docker://registry.example.com:5000/paketo-buildpacks/test-10:test-version-1"`))
+	})
+
+	it("updates digest pinned package dependency", func() {
+		Expect(os.WriteFile(path, []byte(`dependencies = [
+	{ uri = "docker://gcr.io/paketo-buildpacks/test-1@sha256:1234567890abcdef" },
+	{ uri = "docker://gcr.io/paketo-buildpacks/test-2:test-version-2@sha256:fedcba0987654321" },
+]`), 0600)).To(Succeed())
+
+		p := carton.PackageDependency{
+			PackagePath: path,
+			ID:          "gcr.io/paketo-buildpacks/test-1",
+			Version:     "test-version-3",
+		}
+
+		p.Update(carton.WithExitHandler(exitHandler))
+
+		Expect(os.ReadFile(path)).To(libpakTesting.MatchTOML(`[[dependencies]]
+		uri = "docker://gcr.io/paketo-buildpacks/test-1:test-version-3"
+
+	  [[dependencies]]
+		uri = "docker://gcr.io/paketo-buildpacks/test-2:test-version-2@sha256:fedcba0987654321"`))
+	})
+
 	it("updates paketocommunity package dependency", func() {
 		Expect(os.WriteFile(path, []byte(`dependencies = [
 	{ uri = "docker://docker.io/paketocommunity/test-1:test-version-1" },
@@ -222,4 +429,83 @@ group = [
 	  [[dependencies]]
 		uri = "docker://docker.io/paketocommunity/test-2:test-version-2"`))
 	})
+
+	it("fails on no change when the package is unchanged", func() {
+		Expect(os.WriteFile(path, []byte(`dependencies = [
+	{ uri = "docker://gcr.io/paketo-buildpacks/test-1:test-version-1" },
+]`), 0600)).To(Succeed())
+
+		p := carton.PackageDependency{
+			PackagePath:    path,
+			ID:             "gcr.io/paketo-buildpacks/test-1",
+			Version:        "test-version-3",
+			FailOnNoChange: true,
+		}
+
+		p.Update(carton.WithExitHandler(exitHandler))
+
+		exitHandler.AssertNotCalled(t, "Error", mock.Anything)
+		Expect(os.ReadFile(path)).To(ContainSubstring("test-version-3"))
+
+		p.Update(carton.WithExitHandler(exitHandler))
+
+		exitHandler.AssertCalled(t, "Error", mock.MatchedBy(func(err error) bool {
+			return strings.Contains(err.Error(), "no changes were made")
+		}))
+	})
+
+	it("does not fail on no change when one of the files is changed", func() {
+		Expect(os.WriteFile(path, []byte(`dependencies = [
+	{ uri = "docker://gcr.io/paketo-buildpacks/test-1:test-version-1" },
+]`), 0600)).To(Succeed())
+
+		builderPath := filepath.Join(t.TempDir(), "builder.toml")
+		Expect(os.WriteFile(builderPath, []byte(`[[buildpacks]]
+  uri = "docker://gcr.io/paketo-buildpacks/test-2:test-version-2"
+`), 0600)).To(Succeed())
+
+		p := carton.PackageDependency{
+			BuilderPath:    builderPath,
+			PackagePath:    path,
+			ID:             "gcr.io/paketo-buildpacks/test-1",
+			Version:        "test-version-3",
+			FailOnNoChange: true,
+		}
+
+		p.Update(carton.WithExitHandler(exitHandler))
+
+		exitHandler.AssertNotCalled(t, "Error", mock.Anything)
+		Expect(os.ReadFile(path)).To(ContainSubstring("test-version-3"))
+	})
+
+	it("does not change any file when one of them fails to update", func() {
+		Expect(os.WriteFile(path, []byte(`dependencies = [
+	{ uri = "docker://gcr.io/paketo-buildpacks/test-1:test-version-1" },
+]`), 0600)).To(Succeed())
+
+		builderPath := filepath.Join(t.TempDir(), "builder.toml")
+		builder := []byte(`[[buildpacks]]
+  uri = "docker://gcr.io/paketo-buildpacks/test-1:test-version-1"
+`)
+		Expect(os.WriteFile(builderPath, builder, 0600)).To(Succeed())
+
+		buildpackPath := filepath.Join(t.TempDir(), "buildpack.toml")
+		Expect(os.WriteFile(buildpackPath, []byte("not [valid toml"), 0600)).To(Succeed())
+
+		p := carton.PackageDependency{
+			BuilderPath:   builderPath,
+			PackagePath:   path,
+			BuildpackPath: buildpackPath,
+			ID:            "gcr.io/paketo-buildpacks/test-1",
+			Version:       "test-version-3",
+		}
+
+		p.Update(carton.WithExitHandler(exitHandler))
+
+		exitHandler.AssertCalled(t, "Error", mock.MatchedBy(func(err error) bool {
+			return strings.Contains(err.Error(), fmt.Sprintf("unable to update %s, no files were changed", buildpackPath))
+		}))
+		Expect(os.ReadFile(builderPath)).To(Equal(builder))
+		Expect(os.ReadFile(path)).To(ContainSubstring("test-version-1"))
+	})
 }
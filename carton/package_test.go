@@ -19,6 +19,7 @@ package carton_test
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/buildpacks/libcnb/v2/mocks"
@@ -313,6 +314,49 @@ include-files = [
 				Expect(entryWriter.Calls[3].Arguments[0]).To(Equal(filepath.Join(path, "test-include-files")))
 				Expect(entryWriter.Calls[3].Arguments[1]).To(Equal(filepath.Join("test-destination", "test-include-files")))
 			})
+
+			it("excludes dependencies matching a regex filter", func() {
+				carton.Package{
+					Source:                   path,
+					Destination:              "test-destination",
+					IncludeDependencies:      true,
+					CacheLocation:            "testdata",
+					DependencyFilters:        []string{`^1.1.1$`},
+					ExcludeDependencyFilters: []string{`^another-test-id$`},
+				}.Create(
+					carton.WithEntryWriter(entryWriter),
+					carton.WithExecutor(executor),
+					carton.WithExitHandler(exitHandler))
+
+				Expect(entryWriter.Calls).To(HaveLen(4))
+				Expect(entryWriter.Calls[0].Arguments[0]).To(Equal(filepath.Join(path, "buildpack.toml")))
+				Expect(entryWriter.Calls[0].Arguments[1]).To(Equal(filepath.Join("test-destination", "buildpack.toml")))
+
+				Expect(entryWriter.Calls[1].Arguments[0]).To(Equal("testdata/test-sha256-1.toml"))
+				Expect(entryWriter.Calls[1].Arguments[1]).To(Equal(filepath.Join("test-destination", "dependencies/test-sha256-1.toml")))
+				Expect(entryWriter.Calls[2].Arguments[0]).To(Equal("testdata/test-sha256-1/test-uri-1"))
+				Expect(entryWriter.Calls[2].Arguments[1]).To(Equal(filepath.Join("test-destination", "dependencies/test-sha256-1/test-uri-1")))
+
+				Expect(entryWriter.Calls[3].Arguments[0]).To(Equal(filepath.Join(path, "test-include-files")))
+				Expect(entryWriter.Calls[3].Arguments[1]).To(Equal(filepath.Join("test-destination", "test-include-files")))
+			})
+
+			it("fails on an invalid regex filter", func() {
+				carton.Package{
+					Source:                   path,
+					Destination:              "test-destination",
+					IncludeDependencies:      true,
+					CacheLocation:            "testdata",
+					ExcludeDependencyFilters: []string{`-ea(`},
+				}.Create(
+					carton.WithEntryWriter(entryWriter),
+					carton.WithExecutor(executor),
+					carton.WithExitHandler(exitHandler))
+
+				exitHandler.AssertCalled(t, "Error", mock.MatchedBy(func(err error) bool {
+					return strings.Contains(err.Error(), "unable to compile dependency filter -ea(")
+				}))
+			})
 		})
 	})
 
@@ -649,4 +693,22 @@ include-files = [
 			})
 		})
 	})
+
+	context("ResolveCacheLocation", func() {
+		it("keeps a location that is set", func() {
+			Expect(carton.ResolveCacheLocation("/some/cache")).To(Equal("/some/cache"))
+		})
+
+		it("defaults to dependencies in the working directory", func() {
+			original, err := os.Getwd()
+			Expect(err).NotTo(HaveOccurred())
+			defer func() { Expect(os.Chdir(original)).To(Succeed()) }()
+
+			wd, err := filepath.EvalSymlinks(t.TempDir())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.Chdir(wd)).To(Succeed())
+
+			Expect(carton.ResolveCacheLocation("")).To(Equal(filepath.Join(wd, "dependencies")))
+		})
+	})
 }
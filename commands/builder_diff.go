@@ -0,0 +1,95 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/paketo-buildpacks/libpak-tools/carton"
+)
+
+func BuilderDiffCommand() *cobra.Command {
+	d := carton.BuilderDiff{}
+	var output string
+
+	var builderDiffCmd = &cobra.Command{
+		Use:   "diff",
+		Short: "Show buildpack, lifecycle and image changes between two builder.toml files",
+		Run: func(cmd *cobra.Command, args []string) {
+			if d.OldPath == "" {
+				log.Fatal("old must be set")
+			}
+
+			if d.NewPath == "" {
+				log.Fatal("new must be set")
+			}
+
+			changes, err := d.Diff()
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			switch output {
+			case "text":
+				w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+				for _, c := range changes {
+					_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t->\t%s\n", c.Status, c.Kind, c.Name, orNone(c.Old), orNone(c.New))
+				}
+				if err := w.Flush(); err != nil {
+					log.Fatal(err)
+				}
+			case "json":
+				if changes == nil {
+					changes = []carton.BuilderChange{}
+				}
+				if err := json.NewEncoder(os.Stdout).Encode(changes); err != nil {
+					log.Fatal(fmt.Errorf("unable to encode changes\n%w", err))
+				}
+			case "name-only":
+				for _, c := range changes {
+					if c.New != "" {
+						fmt.Printf("%s@%s\n", c.Name, c.New)
+					} else {
+						fmt.Println(c.Name)
+					}
+				}
+			default:
+				log.Fatalf("invalid output %q, must be text, json or name-only", output)
+			}
+		},
+	}
+
+	builderDiffCmd.Flags().StringVar(&d.OldPath, "old", "", "path to the old builder.toml")
+	builderDiffCmd.Flags().StringVar(&d.NewPath, "new", "", "path to the new builder.toml")
+	builderDiffCmd.Flags().StringVar(&output, "output", "text", "output format, text, json or name-only (name@new-version of each change, one per line)")
+
+	return builderDiffCmd
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
@@ -27,6 +27,12 @@ func DependencyCommand() *cobra.Command {
 	}
 
 	dependencyCmd.AddCommand(DependencyUpdateCommand())
+	dependencyCmd.AddCommand(DependencyDiffCommand())
+	dependencyCmd.AddCommand(DependencyListCommand())
+	dependencyCmd.AddCommand(DependencyRefreshEOLCommand())
+	dependencyCmd.AddCommand(DependencyRemoveBuildModuleCommand())
+	dependencyCmd.AddCommand(DependencySetTargetsCommand())
+	dependencyCmd.AddCommand(DependencyValidateCommand())
 
 	return dependencyCmd
 }
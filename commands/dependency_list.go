@@ -0,0 +1,88 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/paketo-buildpacks/libpak-tools/carton"
+)
+
+func DependencyListCommand() *cobra.Command {
+	b := carton.BuildModuleList{}
+	var output string
+
+	var dependencyListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the dependencies of a build module",
+		Run: func(cmd *cobra.Command, args []string) {
+			if b.BuildModulePath == "" {
+				log.Fatal("buildmodule toml path must be set")
+			}
+
+			if output != "text" && output != "json" && output != "name-only" {
+				log.Fatalf("invalid output %q, must be text, json or name-only", output)
+			}
+
+			listings, err := b.List()
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if output == "json" {
+				if err := json.NewEncoder(os.Stdout).Encode(listings); err != nil {
+					log.Fatal(err)
+				}
+				return
+			}
+
+			if output == "name-only" {
+				// dependencies of several arches share an id and version, each is printed once
+				printed := map[string]bool{}
+				for _, l := range listings {
+					name := fmt.Sprintf("%s@%s", l.ID, l.Version)
+					if !printed[name] {
+						fmt.Println(name)
+						printed[name] = true
+					}
+				}
+				return
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "ID\tVERSION\tARCH\tPURL\tCPES\tEOL")
+			for _, l := range listings {
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%s\n", l.ID, l.Version, l.Arch, orNone(strings.Join(l.PURLs, ",")), l.CPECount, orNone(l.EOLDate))
+			}
+			if err := w.Flush(); err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	dependencyListCmd.Flags().StringVar(&b.BuildModulePath, "buildmodule-toml", "", "path to buildpack.toml or extension.toml")
+	dependencyListCmd.Flags().StringVar(&output, "output", "text", "output format, text, json or name-only (id@version of each dependency, one per line)")
+
+	return dependencyListCmd
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"github.com/paketo-buildpacks/libpak-tools/carton"
+)
+
+func DependencyRefreshEOLCommand() *cobra.Command {
+	b := carton.BuildModuleEOL{}
+
+	var dependencyRefreshEOLCmd = &cobra.Command{
+		Use:   "refresh-eol",
+		Short: "Refresh the EOL date of build module dependencies",
+		Run: func(cmd *cobra.Command, args []string) {
+			if b.BuildModulePath == "" {
+				log.Fatal("buildmodule toml path must be set")
+			}
+
+			if b.ID == "" {
+				log.Fatal("id must be set")
+			}
+
+			if b.VersionPattern == "" {
+				log.Fatal("version-pattern must be set")
+			}
+
+			if b.EolID == "" {
+				log.Fatal("eol-id must be set")
+			}
+
+			if b.TOMLIndent < 0 {
+				log.Fatal("toml-indent must not be negative")
+			}
+
+			b.Refresh()
+		},
+	}
+
+	dependencyRefreshEOLCmd.Flags().StringVar(&b.BuildModulePath, "buildmodule-toml", "", "path to buildpack.toml or extension.toml")
+	dependencyRefreshEOLCmd.Flags().StringVar(&b.ID, "id", "", "the id of the dependency")
+	dependencyRefreshEOLCmd.Flags().StringVar(&b.VersionPattern, "version-pattern", "", "the version pattern of the dependencies to refresh")
+	dependencyRefreshEOLCmd.Flags().StringVar(&b.EolID, "eol-id", "", "id of the dependency for looking up the EOL date on the https://endoflife.date/")
+	dependencyRefreshEOLCmd.Flags().StringVar(&b.EolCache, "eol-cache", "", "path to a JSON cache of release cycles, read before and updated after looking up EOL dates")
+	dependencyRefreshEOLCmd.Flags().IntVar(&b.TOMLIndent, "toml-indent", 0, "the number of spaces to indent nested TOML tables with, if not set defaults to 2")
+	dependencyRefreshEOLCmd.Flags().BoolVar(&b.FailOnNoChange, "fail-on-no-change", false, "exit non-zero when the update leaves the file unchanged (default: false)")
+
+	return dependencyRefreshEOLCmd
+}
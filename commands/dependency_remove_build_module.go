@@ -0,0 +1,71 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"github.com/paketo-buildpacks/libpak-tools/carton"
+)
+
+func DependencyRemoveBuildModuleCommand() *cobra.Command {
+	r := carton.BuildModuleRemoval{}
+
+	var dependencyRemoveBuildModuleCmd = &cobra.Command{
+		Use:   "remove-build-module",
+		Short: "Remove build module dependencies",
+		Run: func(cmd *cobra.Command, args []string) {
+			if r.BuildModulePath == "" {
+				log.Fatal("buildmodule toml path must be set")
+			}
+
+			if r.ID == "" {
+				log.Fatal("id must be set")
+			}
+
+			if r.VersionPattern == "" && r.VersionConstraint == "" {
+				log.Fatal("version-pattern or version-constraint must be set")
+			}
+
+			if r.VersionPattern != "" && r.VersionConstraint != "" {
+				log.Fatal("version-constraint cannot be combined with version-pattern")
+			}
+
+			if r.TOMLIndent < 0 {
+				log.Fatal("toml-indent must not be negative")
+			}
+
+			r.Remove()
+		},
+	}
+
+	dependencyRemoveBuildModuleCmd.Flags().StringVar(&r.BuildModulePath, "buildmodule-toml", "", "path to buildpack.toml or extension.toml")
+	dependencyRemoveBuildModuleCmd.Flags().StringVar(&r.ID, "id", "", "the id of the dependencies to remove")
+	dependencyRemoveBuildModuleCmd.Flags().StringVar(&r.Arch, "arch", "", "only remove dependencies of this arch (default: all arches)")
+	dependencyRemoveBuildModuleCmd.Flags().BoolVar(&r.NormalizeArch, "normalize-arch", true, "map alternate arch spellings like x86_64 and aarch64 to amd64 and arm64 before matching")
+	dependencyRemoveBuildModuleCmd.Flags().StringVar(&r.VersionPattern, "version-pattern", "", "the version pattern of the dependencies to remove, a regular expression that should be anchored with ^ and $ to avoid partial matches")
+	dependencyRemoveBuildModuleCmd.Flags().StringVar(&r.VersionConstraint, "version-constraint", "", "a SemVer range like '<17.0.0' that selects the dependencies to remove instead of version-pattern")
+	dependencyRemoveBuildModuleCmd.Flags().BoolVar(&r.ExactVersion, "exact-version", false, "treat version-pattern as a literal version that must match the whole dependency version")
+	dependencyRemoveBuildModuleCmd.Flags().StringVar(&r.MetadataSubkey, "metadata-subkey", "", "remove dependencies grouped under [[metadata.dependencies.<subkey>]] instead of [[metadata.dependencies]]")
+	dependencyRemoveBuildModuleCmd.Flags().IntVar(&r.TOMLIndent, "toml-indent", 0, "the number of spaces to indent nested TOML tables with, if not set defaults to 2")
+	dependencyRemoveBuildModuleCmd.Flags().BoolVar(&r.Backup, "backup", false, "copy the build module to <path>.bak before it is rewritten, the copy is kept if the write fails")
+	dependencyRemoveBuildModuleCmd.Flags().BoolVar(&r.RequireMatch, "require-match", false, "fail if no dependency matches the id, arch and version-pattern (default: false)")
+
+	return dependencyRemoveBuildModuleCmd
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"github.com/paketo-buildpacks/libpak-tools/carton"
+)
+
+func DependencySetTargetsCommand() *cobra.Command {
+	b := carton.BuildModuleTargets{}
+
+	var dependencySetTargetsCmd = &cobra.Command{
+		Use:   "set-targets",
+		Short: "Set the targets or stacks of all build module dependencies",
+		Run: func(cmd *cobra.Command, args []string) {
+			if b.BuildModulePath == "" {
+				log.Fatal("buildmodule toml path must be set")
+			}
+
+			if len(b.Targets) == 0 && len(b.Stacks) == 0 {
+				log.Fatal("targets or stacks must be set")
+			}
+
+			if b.TOMLIndent < 0 {
+				log.Fatal("toml-indent must not be negative")
+			}
+
+			b.Update()
+		},
+	}
+
+	dependencySetTargetsCmd.Flags().StringVar(&b.BuildModulePath, "buildmodule-toml", "", "path to buildpack.toml or extension.toml")
+	dependencySetTargetsCmd.Flags().StringVar(&b.ID, "id", "", "only update dependencies with this id (default: all dependencies)")
+	dependencySetTargetsCmd.Flags().StringSliceVar(&b.Targets, "targets", []string{}, "comma separated targets to set on each dependency, e.g. linux/amd64,linux/arm64")
+	dependencySetTargetsCmd.Flags().StringSliceVar(&b.Stacks, "stacks", []string{}, "comma separated stacks to set on each dependency, e.g. io.buildpacks.stacks.jammy,*")
+	dependencySetTargetsCmd.Flags().BoolVar(&b.DryRun, "dry-run", false, "print the changes without writing them (default: false)")
+	dependencySetTargetsCmd.Flags().IntVar(&b.TOMLIndent, "toml-indent", 0, "the number of spaces to indent nested TOML tables with, if not set defaults to 2")
+	dependencySetTargetsCmd.Flags().BoolVar(&b.FailOnNoChange, "fail-on-no-change", false, "exit non-zero when the update leaves the file unchanged (default: false)")
+
+	return dependencySetTargetsCmd
+}
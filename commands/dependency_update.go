@@ -30,6 +30,7 @@ func DependencyUpdateCommand() *cobra.Command {
 	dependencyUpdateCmd.AddCommand(DependencyUpdateLifecycleCommand())
 	dependencyUpdateCmd.AddCommand(DependencyUpdatePackageCommand())
 	dependencyUpdateCmd.AddCommand(DependencyUpdateBuildModuleCommand())
+	dependencyUpdateCmd.AddCommand(DependencyUpdateBuildModuleBatchCommand())
 
 	return dependencyUpdateCmd
 }
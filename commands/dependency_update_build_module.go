@@ -17,15 +17,21 @@
 package commands
 
 import (
+	"fmt"
 	"log"
+	"regexp"
 
 	"github.com/spf13/cobra"
 
 	"github.com/paketo-buildpacks/libpak-tools/carton"
+	"github.com/paketo-buildpacks/libpak-tools/internal"
 )
 
 func DependencyUpdateBuildModuleCommand() *cobra.Command {
 	b := carton.BuildModuleDependency{}
+	var archURIs, archSHA256s, archAliases internal.KeyValueFlags
+	var fromFile string
+	var strict bool
 
 	var dependencyUpdateBuildModuleCmd = &cobra.Command{
 		Use:   "build-module",
@@ -39,32 +45,79 @@ func DependencyUpdateBuildModuleCommand() *cobra.Command {
 				log.Fatal("id must be set")
 			}
 
+			if b.TOMLIndent < 0 {
+				log.Fatal("toml-indent must not be negative")
+			}
+
 			if b.Arch == "" {
 				b.Arch = "amd64"
 			}
 
-			if b.SHA256 == "" {
-				log.Fatal("sha256 must be set")
+			b.ArchAliases = archAliases.Map()
+
+			if fromFile != "" {
+				if len(archURIs) > 0 || len(archSHA256s) > 0 {
+					log.Fatal("from-file cannot be combined with arch-uri or arch-sha256")
+				}
+
+				if b.Algorithm != "" && b.Algorithm != carton.DefaultChecksumAlgorithm {
+					log.Fatalf("from-file computes a sha256 digest and cannot be combined with algorithm %s", b.Algorithm)
+				}
+
+				sha256, err := internal.FileSHA256(fromFile)
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				if strict && b.SHA256 != "" && b.SHA256 != sha256 {
+					log.Fatalf("sha256 %s does not match %s computed from %s", b.SHA256, sha256, fromFile)
+				}
+
+				b.SHA256 = sha256
+				// otherwise the algorithm of an existing checksum, e.g. sha512, would be kept for the sha256 digest
+				b.Algorithm = carton.DefaultChecksumAlgorithm
 			}
 
-			if b.URI == "" {
-				log.Fatal("uri must be set")
+			if len(archURIs) > 0 || len(archSHA256s) > 0 {
+				archValues, err := parseArchValues(archURIs, archSHA256s)
+				if err != nil {
+					log.Fatal(err)
+				}
+				b.ArchValues = archValues
+			} else {
+				if b.SHA256 == "" {
+					log.Fatal("sha256 must be set")
+				}
+
+				if b.URI == "" {
+					log.Fatal("uri must be set")
+				}
 			}
 
 			if b.Version == "" {
 				log.Fatal("version must be set")
 			}
 
-			if b.VersionPattern == "" {
-				log.Fatal("version-pattern must be set")
+			if b.VersionPattern == "" && b.MatchURI == "" && b.VersionConstraint == "" {
+				log.Fatal("version-pattern, version-constraint or match-uri must be set")
+			}
+
+			if b.VersionConstraint != "" && (b.VersionPattern != "" || b.MatchURI != "") {
+				log.Fatal("version-constraint cannot be combined with version-pattern or match-uri")
 			}
 
 			if b.PURL == "" {
 				b.PURL = b.Version
 			}
 
+			// purl and cpe patterns match part of a larger string, so an exact version is escaped but not anchored
+			versionPattern := b.VersionPattern
+			if b.ExactVersion {
+				versionPattern = regexp.QuoteMeta(b.VersionPattern)
+			}
+
 			if b.PURLPattern == "" {
-				b.PURLPattern = b.VersionPattern
+				b.PURLPattern = versionPattern
 			}
 
 			if b.CPE == "" {
@@ -72,7 +125,7 @@ func DependencyUpdateBuildModuleCommand() *cobra.Command {
 			}
 
 			if b.CPEPattern == "" {
-				b.CPEPattern = b.VersionPattern
+				b.CPEPattern = versionPattern
 			}
 
 			b.Update()
@@ -81,18 +134,64 @@ func DependencyUpdateBuildModuleCommand() *cobra.Command {
 
 	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.BuildModulePath, "buildmodule-toml", "", "path to buildpack.toml or extension.toml")
 	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.ID, "id", "", "the id of the dependency")
-	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.Arch, "arch", "", "the arch of the dependency")
+	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.Arch, "arch", "amd64", "the arch of the dependency, selects which dependency block is updated")
 	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.SHA256, "sha256", "", "the new sha256 of the dependency")
+	dependencyUpdateBuildModuleCmd.Flags().StringVar(&fromFile, "from-file", "", "a local copy of the dependency whose sha256 is computed and used instead of --sha256")
+	dependencyUpdateBuildModuleCmd.Flags().BoolVar(&strict, "strict", false, "fail if --sha256 is also set and does not match the sha256 computed from --from-file (default: false)")
 	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.URI, "uri", "", "the new uri of the dependency")
+	dependencyUpdateBuildModuleCmd.Flags().BoolVar(&b.NormalizeArch, "normalize-arch", true, "map alternate arch spellings like x86_64 and aarch64 to amd64 and arm64 before matching")
+	dependencyUpdateBuildModuleCmd.Flags().Var(&archAliases, "arch-alias", "an additional arch spelling to normalize, as from=to (repeatable)")
+	dependencyUpdateBuildModuleCmd.Flags().Var(&archURIs, "arch-uri", "the new uri of the dependency for an arch, as arch=uri (repeatable, replaces --arch & --uri)")
+	dependencyUpdateBuildModuleCmd.Flags().Var(&archSHA256s, "arch-sha256", "the new sha256 of the dependency for an arch, as arch=sha256 (repeatable, replaces --arch & --sha256)")
 	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.Version, "version", "", "the new version of the dependency")
-	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.VersionPattern, "version-pattern", "", "the version pattern of the dependency")
+	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.BuildNumber, "build-number", "", "the new build number of the dependency, written to revision if present or build")
+	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.VersionPattern, "version-pattern", "", "the version pattern of the dependency, a regular expression that should be anchored with ^ and $ to avoid partial matches")
+	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.VersionConstraint, "version-constraint", "", "a SemVer range like '>=17.0.0 <18.0.0' that selects the dependencies to update instead of version-pattern")
+	dependencyUpdateBuildModuleCmd.Flags().BoolVar(&b.ExactVersion, "exact-version", false, "treat version-pattern as a literal version that must match the whole dependency version")
 	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.PURL, "purl", "", "the new purl version of the dependency, if not set defaults to version")
 	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.PURLPattern, "purl-pattern", "", "the purl version pattern of the dependency, if not set defaults to version-pattern")
 	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.CPE, "cpe", "", "the new version use in all CPEs, if not set defaults to version")
 	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.CPEPattern, "cpe-pattern", "", "the cpe version pattern of the dependency, if not set defaults to version-pattern")
+	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.Name, "name", "", "the new version to use in the dependency name, if not set the name is not changed")
+	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.NamePattern, "name-pattern", "", "the version pattern of the dependency name, if not set defaults to the current version")
+	dependencyUpdateBuildModuleCmd.Flags().StringArrayVar(&b.Stacks, "stack", []string{}, "one or more stacks that replace the stacks of the updated dependency, if not set the stacks are not changed")
 	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.Source, "source", "", "the new uri of the dependency source")
 	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.SourceSHA256, "source-sha256", "", "the new sha256 of the dependency source")
+	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.Algorithm, "algorithm", "", "the algorithm of the sha256 & source-sha256 digests (e.g. sha512), if not set keeps the existing algorithm or defaults to sha256")
 	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.EolID, "eol-id", "", "id of the dependency for looking up the EOL date on the https://endoflife.date/")
+	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.EolCache, "eol-cache", "", "path to a JSON cache of release cycles, read before and updated after looking up EOL dates")
+	dependencyUpdateBuildModuleCmd.Flags().IntVar(&b.TOMLIndent, "toml-indent", 0, "the number of spaces to indent nested TOML tables with, if not set defaults to 2")
+	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.MatchURI, "match-uri", "", "update the one dependency with this uri, instead of those matching version-pattern")
+	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.MetadataSubkey, "metadata-subkey", "", "update dependencies grouped under [[metadata.dependencies.<subkey>]] instead of [[metadata.dependencies]]")
+	dependencyUpdateBuildModuleCmd.Flags().BoolVar(&b.Quiet, "quiet", false, "do not log a summary of the update, warnings and errors are still reported (default: false)")
+	dependencyUpdateBuildModuleCmd.Flags().BoolVar(&b.CheckURI, "check-uri", false, "check that the new uri and source are reachable, without downloading them, before updating (default: false)")
+	dependencyUpdateBuildModuleCmd.Flags().BoolVar(&b.Backup, "backup", false, "copy the build module to <path>.bak before it is rewritten, the copy is kept if the write fails")
+	dependencyUpdateBuildModuleCmd.Flags().StringVar(&b.NotifyWebhook, "notify-webhook", "", "a URL that is sent a JSON payload when the dependency is changed")
+	dependencyUpdateBuildModuleCmd.Flags().BoolVar(&b.AddIfMissing, "add-if-missing", false, "append a new dependency for each arch that no existing dependency matches, copied from one with the same id if there is one (default: false)")
+	dependencyUpdateBuildModuleCmd.Flags().BoolVar(&b.RequireMatch, "require-match", false, "fail if no dependency matches the id, arch and version-pattern (default: false)")
+	dependencyUpdateBuildModuleCmd.Flags().BoolVar(&b.FailOnNoChange, "fail-on-no-change", false, "exit non-zero when the update leaves the file unchanged (default: false)")
 
 	return dependencyUpdateBuildModuleCmd
 }
+
+func parseArchValues(archURIs internal.KeyValueFlags, archSHA256s internal.KeyValueFlags) (map[string]carton.ArchValue, error) {
+	uris := archURIs.Map()
+	sha256s := archSHA256s.Map()
+
+	archValues := map[string]carton.ArchValue{}
+	for arch, uri := range uris {
+		sha256, found := sha256s[arch]
+		if !found {
+			return nil, fmt.Errorf("arch-sha256 must be set for arch %s", arch)
+		}
+		archValues[arch] = carton.ArchValue{URI: uri, SHA256: sha256}
+	}
+
+	for arch := range sha256s {
+		if _, found := uris[arch]; !found {
+			return nil, fmt.Errorf("arch-uri must be set for arch %s", arch)
+		}
+	}
+
+	return archValues, nil
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/paketo-buildpacks/libpak-tools/carton"
+)
+
+func DependencyUpdateBuildModuleBatchCommand() *cobra.Command {
+	b := carton.BuildModuleBatch{}
+	var updatesPath string
+
+	var dependencyUpdateBuildModuleBatchCmd = &cobra.Command{
+		Use:   "build-module-batch",
+		Short: "Update several build module dependencies from an updates file",
+		Run: func(cmd *cobra.Command, args []string) {
+			if b.BuildModulePath == "" {
+				log.Fatal("buildmodule toml path must be set")
+			}
+
+			if updatesPath == "" {
+				log.Fatal("updates must be set")
+			}
+
+			if b.TOMLIndent < 0 {
+				log.Fatal("toml-indent must not be negative")
+			}
+
+			updates, err := carton.ReadBuildModuleUpdates(updatesPath)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			results, err := b.Apply(updates)
+			writeBatchResults(os.Stdout, results, err == nil)
+
+			if err != nil {
+				log.Fatal(err)
+			}
+		},
+	}
+
+	dependencyUpdateBuildModuleBatchCmd.Flags().StringVar(&b.BuildModulePath, "buildmodule-toml", "", "path to buildpack.toml or extension.toml")
+	dependencyUpdateBuildModuleBatchCmd.Flags().StringVar(&updatesPath, "updates", "", "path to a TOML file with an [[updates]] entry per dependency update")
+	dependencyUpdateBuildModuleBatchCmd.Flags().IntVar(&b.TOMLIndent, "toml-indent", 0, "the number of spaces to indent nested TOML tables with, if not set defaults to 2")
+
+	return dependencyUpdateBuildModuleBatchCmd
+}
+
+// writeBatchResults reports the outcome of each update and the totals. If the batch was not applied, the build module
+// was not written and the updates that did not fail are reported as skipped.
+func writeBatchResults(w io.Writer, results []carton.BuildModuleUpdateResult, applied bool) {
+	updated, skipped, failed := 0, 0, 0
+	for _, r := range results {
+		switch {
+		case r.Err != nil:
+			failed++
+			_, _ = fmt.Fprintf(w, "  FAILED   %s %s %s: %s\n", r.ID, r.Arch, r.Version, strings.ReplaceAll(r.Err.Error(), "\n", " "))
+		case !applied:
+			skipped++
+			_, _ = fmt.Fprintf(w, "  SKIPPED  %s %s %s: not applied\n", r.ID, r.Arch, r.Version)
+		default:
+			updated++
+			_, _ = fmt.Fprintf(w, "  UPDATED  %s %s %s\n", r.ID, r.Arch, r.Version)
+		}
+	}
+	_, _ = fmt.Fprintf(w, "  %d updated, %d skipped, %d failed\n", updated, skipped, failed)
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libpak-tools/carton"
+)
+
+func testDependencyUpdateBuildModuleBatch(t *testing.T, _ spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		results []carton.BuildModuleUpdateResult
+	)
+
+	it.Before(func() {
+		results = []carton.BuildModuleUpdateResult{
+			{ID: "one", Arch: "amd64", Version: "1.0.1"},
+			{ID: "two", Arch: "amd64", Version: "2.0.1", Err: fmt.Errorf("sha256 must be set")},
+		}
+	})
+
+	it("reports the updates that did not fail as updated when the batch was applied", func() {
+		out := &bytes.Buffer{}
+		writeBatchResults(out, results[:1], true)
+
+		Expect(out.String()).To(Equal("  UPDATED  one amd64 1.0.1\n  1 updated, 0 skipped, 0 failed\n"))
+	})
+
+	it("reports the updates that did not fail as skipped when the batch was not applied", func() {
+		out := &bytes.Buffer{}
+		writeBatchResults(out, results, false)
+
+		Expect(out.String()).To(Equal(`  SKIPPED  one amd64 1.0.1: not applied
+  FAILED   two amd64 2.0.1: sha256 must be set
+  0 updated, 1 skipped, 1 failed
+`))
+	})
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+)
+
+func testDependencyUpdateBuildModule(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		path string
+	)
+
+	it.Before(func() {
+		path = filepath.Join(t.TempDir(), "buildpack.toml")
+		Expect(os.WriteFile(path, []byte(`api = "0.7"
+
+[[metadata.dependencies]]
+  id = "test-id"
+  version = "17.0.1"
+  uri = "test-uri-1"
+  sha256 = "test-sha256-1"
+`), 0600)).To(Succeed())
+	})
+
+	it("looks up the EOL date with --eol-id", func() {
+		t.Setenv("BP_OFFLINE", "true")
+
+		cache := filepath.Join(t.TempDir(), "eol-cache.json")
+		Expect(os.WriteFile(cache, []byte(`{"java": [{"cycle": "17", "eol": "2029-09-30"}]}`), 0600)).To(Succeed())
+
+		cmd := DependencyUpdateBuildModuleCommand()
+		cmd.SetArgs([]string{
+			"--buildmodule-toml", path,
+			"--id", "test-id",
+			"--version", "17.0.2",
+			"--version-pattern", `^17\.0\.1$`,
+			"--uri", "test-uri-2",
+			"--sha256", "test-sha256-2",
+			"--eol-id", "java",
+			"--eol-cache", cache,
+			"--quiet",
+		})
+
+		Expect(cmd.Execute()).To(Succeed())
+		Expect(os.ReadFile(path)).To(ContainSubstring(`deprecation_date = "2029-09-30T00:00:00Z"`))
+	})
+
+	it("leaves the EOL date alone without --eol-id", func() {
+		cmd := DependencyUpdateBuildModuleCommand()
+		cmd.SetArgs([]string{
+			"--buildmodule-toml", path,
+			"--id", "test-id",
+			"--version", "17.0.2",
+			"--version-pattern", `^17\.0\.1$`,
+			"--uri", "test-uri-2",
+			"--sha256", "test-sha256-2",
+			"--quiet",
+		})
+
+		Expect(cmd.Execute()).To(Succeed())
+		Expect(os.ReadFile(path)).NotTo(ContainSubstring("deprecation_date"))
+	})
+}
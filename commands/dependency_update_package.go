@@ -31,8 +31,8 @@ func DependencyUpdatePackageCommand() *cobra.Command {
 		Use:   "package",
 		Short: "Update a package dependency",
 		Run: func(cmd *cobra.Command, args []string) {
-			if p.BuilderPath == "" && p.BuildpackPath == "" && p.PackagePath == "" {
-				log.Fatal("builder-toml, buildpack-toml, or package-toml must be set")
+			if p.BuilderPath == "" && p.BuildpackPath == "" && p.PackagePath == "" && p.ExtensionPath == "" {
+				log.Fatal("builder-toml, buildpack-toml, extension-toml, or package-toml must be set")
 			}
 
 			if p.ID == "" {
@@ -43,15 +43,23 @@ func DependencyUpdatePackageCommand() *cobra.Command {
 				log.Fatal("version must be set")
 			}
 
+			if p.TOMLIndent < 0 {
+				log.Fatal("toml-indent must not be negative")
+			}
+
 			p.Update()
 		},
 	}
 
 	dependencyUpdatePackageCmd.Flags().StringVar(&p.BuilderPath, "builder-toml", "", "path to builder.toml")
 	dependencyUpdatePackageCmd.Flags().StringVar(&p.BuildpackPath, "buildpack-toml", "", "path to buildpack.toml")
+	dependencyUpdatePackageCmd.Flags().StringVar(&p.ExtensionPath, "extension-toml", "", "path to extension.toml")
 	dependencyUpdatePackageCmd.Flags().StringVar(&p.ID, "id", "", "the id of the dependency")
 	dependencyUpdatePackageCmd.Flags().StringVar(&p.PackagePath, "package-toml", "", "path to package.toml")
 	dependencyUpdatePackageCmd.Flags().StringVar(&p.Version, "version", "", "the new version of the dependency")
+	dependencyUpdatePackageCmd.Flags().IntVar(&p.TOMLIndent, "toml-indent", 0, "the number of spaces to indent nested TOML tables with, if not set defaults to 2")
+	dependencyUpdatePackageCmd.Flags().StringVar(&p.URIPrefix, "uri-prefix", "docker://", "the scheme of the image uris to update in builder.toml and package.toml, uris without a scheme and urn:cnb:registry: uris are always updated")
+	dependencyUpdatePackageCmd.Flags().BoolVar(&p.FailOnNoChange, "fail-on-no-change", false, "exit non-zero when the update leaves the file(s) unchanged (default: false)")
 
 	return dependencyUpdatePackageCmd
 }
@@ -0,0 +1,67 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/paketo-buildpacks/libpak-tools/carton"
+)
+
+func DependencyValidateCommand() *cobra.Command {
+	b := carton.BuildModuleValidation{}
+
+	var dependencyValidateCmd = &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the dependencies of a build module",
+		Run: func(cmd *cobra.Command, args []string) {
+			if b.BuildModulePath == "" {
+				log.Fatal("buildmodule toml path must be set")
+			}
+
+			results, err := b.Validate()
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			failed := 0
+			for _, r := range results {
+				if r.Valid() {
+					fmt.Printf("  VALID    %s %s\n", r.ID, r.Version)
+				} else {
+					failed++
+					fmt.Printf("  INVALID  %s %s: %s\n", r.ID, r.Version, strings.Join(r.Problems, ", "))
+				}
+			}
+			fmt.Printf("  %d valid, %d invalid\n", len(results)-failed, failed)
+
+			if failed > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	dependencyValidateCmd.Flags().StringVar(&b.BuildModulePath, "buildmodule-toml", "", "path to buildpack.toml or extension.toml")
+	dependencyValidateCmd.Flags().BoolVar(&b.SkipURICheck, "skip-uri-check", false, "do not check that dependency uris are reachable, for offline runs (default: false)")
+
+	return dependencyValidateCmd
+}
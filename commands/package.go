@@ -28,6 +28,7 @@ func PackageCommand() *cobra.Command {
 
 	packageCmd.AddCommand(PackageCompileCommand())
 	packageCmd.AddCommand(PackageBundleCommand())
+	packageCmd.AddCommand(PackageFromDirCommand())
 
 	return packageCmd
 }
@@ -17,26 +17,114 @@
 package commands
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
 	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 
+	"github.com/paketo-buildpacks/libpak-tools/internal"
 	"github.com/paketo-buildpacks/libpak-tools/packager"
 )
 
 func PackageBundleCommand() *cobra.Command {
 	p := packager.NewBundleBuildpack()
+	var printComposition bool
+	var buildpacksFile string
+	var output string
+	var compileOnly bool
+	var destination string
 
 	var packageBuildpackCmd = &cobra.Command{
 		Use:   "bundle",
 		Short: "Compile and package a single buildpack (component & composite)",
 		Run: func(cmd *cobra.Command, args []string) {
+			p.Verbose = verbose
+
+			if p.PlatformAPI != "" {
+				if err := packager.ValidatePlatformAPI(p.PlatformAPI); err != nil {
+					log.Fatal(err)
+				}
+			}
+
+			if err := packager.ValidateFlatten(p.Flatten); err != nil {
+				log.Fatal(err)
+			}
+
+			if p.PullPolicy != "" {
+				if err := packager.ValidatePullPolicy(p.PullPolicy); err != nil {
+					log.Fatal(err)
+				}
+			}
+
+			if p.Sign && !p.Publish {
+				log.Fatal("sign requires publish")
+			}
+
+			if p.OutputFile != "" && p.Publish {
+				log.Fatal("output-file and publish cannot both be set")
+			}
+
+			if p.OutputFile != "" && buildpacksFile != "" {
+				log.Fatal("output-file and buildpacks-file cannot both be set")
+			}
+
+			if p.SummaryFile != "" && buildpacksFile != "" {
+				log.Fatal("summary-file and buildpacks-file cannot both be set")
+			}
+
+			// each buildpack of a batch is published as its own id and read from its own directory
+			if (p.RegistryName != "" || p.ConfigDir != "") && buildpacksFile != "" {
+				log.Fatal("registry-name and config-dir cannot be combined with buildpacks-file")
+			}
+
+			if compileOnly && destination == "" {
+				log.Fatal("compile-only requires destination")
+			}
+
+			if destination != "" && !compileOnly {
+				log.Fatal("destination requires compile-only")
+			}
+
+			if compileOnly && (p.Publish || p.OutputFile != "" || p.SummaryFile != "" || buildpacksFile != "") {
+				log.Fatal("compile-only cannot be combined with publish, output-file, summary-file or buildpacks-file")
+			}
+
+			if err := resolveBundlePaths(&p); err != nil {
+				log.Fatal(err)
+			}
+
+			if buildpacksFile != "" {
+				bundleBuildpacksFile(p, buildpacksFile)
+				return
+			}
+
+			if isPathPattern(p.BuildpackPath) {
+				if p.BuildpackID != "" || p.RegistryName != "" || p.ConfigDir != "" || p.OutputFile != "" || p.SummaryFile != "" || compileOnly || printComposition {
+					log.Fatal("a buildpack-path pattern cannot be combined with buildpack-id, registry-name, config-dir, output-file, summary-file, compile-only or print-composition")
+				}
+
+				entries, err := packager.ExpandBuildpackPaths(p.BuildpackPath)
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				bundleBatch(p, entries)
+				return
+			}
+
 			if p.BuildpackID == "" && p.BuildpackPath == "" {
 				log.Fatal("buildpack-id or buildpack-path must be set")
 			}
 
 			if p.BuildpackPath != "" && p.BuildpackID == "" {
-				log.Fatal("buildpack-id and buildpack-path must both be set")
+				if err := p.InferBuildpackID(); err != nil {
+					log.Fatal(err)
+				}
 			}
 
 			if p.BuildpackID != "" && p.BuildpackPath == "" {
@@ -45,32 +133,164 @@ func PackageBundleCommand() *cobra.Command {
 				}
 			}
 
+			if printComposition {
+				if err := writeComposition(p, output); err != nil {
+					log.Fatal(err)
+				}
+				return
+			}
+
 			if p.BuildpackVersion == "" {
 				if err := p.InferBuildpackVersion(); err != nil {
 					log.Fatal(err)
 				}
 			}
 
+			if compileOnly {
+				if err := p.Compile(destination); err != nil {
+					log.Fatal(err)
+				}
+				return
+			}
+
 			if p.RegistryName == "" {
 				p.RegistryName = p.BuildpackID
 			}
 
-			err := p.Execute()
-			if err != nil {
+			if err := p.Execute(); err != nil {
 				log.Fatal(err)
 			}
+
+			// progress goes to stderr, so the digest can be captured from stdout
+			if p.ResultDigest != "" {
+				fmt.Println(p.ResultDigest)
+			}
 		},
 	}
 
-	packageBuildpackCmd.Flags().StringVar(&p.BuildpackID, "buildpack-id", "", "id of the buildpack to use")
-	packageBuildpackCmd.Flags().StringVar(&p.BuildpackPath, "buildpack-path", "", "path to buildpack directory")
+	packageBuildpackCmd.Flags().StringVar(&p.BuildpackID, "buildpack-id", "", "id of the buildpack to use (default: the id in buildpack.toml of buildpack-path)")
+	packageBuildpackCmd.Flags().StringVar(&p.BuildpackPath, "buildpack-path", "", "path to buildpack directory, or a glob like ./buildpacks/* to package each matching buildpack in turn")
+	packageBuildpackCmd.Flags().StringVar(&p.ConfigDir, "config-dir", "", "directory with the buildpack.toml and package.toml of a composite buildpack, not supported for component buildpacks (default: buildpack-path)")
 	packageBuildpackCmd.Flags().StringVar(&p.BuildpackVersion, "version", "", "version to substitute into buildpack.toml/extension.toml")
 	packageBuildpackCmd.Flags().StringVar(&p.CacheLocation, "cache-location", "", "path to cache downloaded dependencies (default: $PWD/dependencies)")
 	packageBuildpackCmd.Flags().BoolVar(&p.IncludeDependencies, "include-dependencies", false, "whether to include dependencies (default: false)")
 	packageBuildpackCmd.Flags().StringArrayVar(&p.DependencyFilters, "dependency-filter", []string{}, "one or more filters that are applied to exclude dependencies")
 	packageBuildpackCmd.Flags().BoolVar(&p.StrictDependencyFilters, "strict-filters", false, "require filter to match all data or just some data (default: false)")
+	packageBuildpackCmd.Flags().StringArrayVar(&p.ExcludeDependencyFilters, "dependency-filter-regex", []string{}, "one or more regular expressions, dependencies whose id or version match any of them are excluded")
 	packageBuildpackCmd.Flags().StringVar(&p.RegistryName, "registry-name", "", "prefix for the registry to publish to (default: your buildpack id)")
 	packageBuildpackCmd.Flags().BoolVar(&p.Publish, "publish", false, "publish the buildpack to a buildpack registry (default: false)")
+	packageBuildpackCmd.Flags().StringVar(&p.OutputFile, "output-file", "", "write the buildpack to this .cnb file with --format file instead of creating an image")
+	packageBuildpackCmd.Flags().StringVar(&p.SummaryFile, "summary-file", "", "write a JSON summary of what was packaged to this file, also when packaging fails")
+	packageBuildpackCmd.Flags().Var((*internal.KeyValueFlags)(&p.Labels), "label", "one or more labels to add to the packaged image, e.g. org.opencontainers.image.source=<url>")
+	packageBuildpackCmd.Flags().StringVar(&p.PullPolicy, "pull-policy", "", "pull policy passed to pack, always, never or if-not-present (default: $BP_PULL_POLICY or if-not-present)")
+	packageBuildpackCmd.Flags().DurationVar(&p.PackageTimeout, "package-timeout", 0, "maximum time pack buildpack package may run, e.g. 30m (default: no limit)")
+	packageBuildpackCmd.Flags().StringVar(&p.ContainerEngine, "container-engine", "", "container CLI used to clean up images, docker or podman (default: $BP_CONTAINER_ENGINE or docker)")
+	packageBuildpackCmd.Flags().StringArrayVar(&p.AllowedRegistries, "allowed-registry", []string{}, "one or more registry hosts that may be published to (default: any)")
+	packageBuildpackCmd.Flags().StringVar(&p.GitBinary, "git-binary", "git", "path to the git binary used to infer the buildpack version")
+	packageBuildpackCmd.Flags().StringArrayVar(&p.GitArgs, "git-arg", []string{}, "one or more global git arguments passed before git describe, each split on whitespace, e.g. -c safe.directory=*")
+	packageBuildpackCmd.Flags().BoolVar(&p.Sign, "sign", false, "sign the published image with cosign sign, using the key in COSIGN_KEY or keyless signing (default: false)")
+	packageBuildpackCmd.Flags().StringVar(&p.CosignBinary, "cosign-binary", "cosign", "path to the cosign binary used to sign the published image")
+	packageBuildpackCmd.Flags().StringVar(&p.Flatten, "flatten", "auto", "whether pack flattens the buildpack, auto, true or false (auto flattens composites unless BP_FLATTEN_DISABLED is set)")
+	packageBuildpackCmd.Flags().StringVar(&p.OS, "os", "", "operating system to package for, linux or windows (default: $BP_OS or linux)")
+	packageBuildpackCmd.Flags().BoolVar(&p.StrictArch, "strict-arch", false, "fail, instead of warning, when building locally for another arch than the host without qemu emulation (default: false)")
+	packageBuildpackCmd.Flags().StringVar(&p.PlatformAPI, "platform-api", "", "platform API for pack to target, e.g. 0.12, passed to pack as CNB_PLATFORM_API (default: pack's own)")
+	packageBuildpackCmd.Flags().StringVar(&buildpacksFile, "buildpacks-file", "", "path to a file listing one buildpack id or id@version per line to package in sequence, paths are inferred from BP_ROOT")
+	packageBuildpackCmd.Flags().BoolVar(&printComposition, "print-composition", false, "print the child buildpacks of a composite buildpack instead of packaging it (default: false)")
+	packageBuildpackCmd.Flags().BoolVar(&compileOnly, "compile-only", false, "only compile the buildpack into --destination, without running pack or cleaning up images (default: false)")
+	packageBuildpackCmd.Flags().StringVar(&destination, "destination", "", "directory the buildpack is compiled into with --compile-only")
+	packageBuildpackCmd.Flags().StringVar(&output, "output", "text", "output format of --print-composition, text, json or name-only")
+
+	_ = packageBuildpackCmd.RegisterFlagCompletionFunc("buildpack-id", completeBuildpackIDs)
 
 	return packageBuildpackCmd
 }
+
+// completeBuildpackIDs completes the ids of the buildpacks cloned under BP_ROOT
+func completeBuildpackIDs(_ *cobra.Command, _ []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	ids, err := packager.ListBuildpackIDs()
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveError
+	}
+
+	var completions []string
+	for _, id := range ids {
+		if strings.HasPrefix(id, toComplete) {
+			completions = append(completions, id)
+		}
+	}
+
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+func bundleBuildpacksFile(p packager.BundleBuildpack, path string) {
+	entries, err := packager.ReadBuildpacksFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	bundleBatch(p, entries)
+}
+
+// isPathPattern returns true if path is a glob pattern, like ./buildpacks/*
+func isPathPattern(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// bundleBatch packages each entry in turn, prints a summary and exits with 1 if any failed. The summary of each entry
+// is progress, only the final count is written to stdout.
+func bundleBatch(p packager.BundleBuildpack, entries []packager.BatchEntry) {
+	results := p.ExecuteBatch(entries)
+
+	var progress io.Writer = os.Stderr
+	if p.Progress != nil {
+		progress = p.Progress
+	}
+
+	failed := 0
+	fmt.Fprintln(progress, "➜ Summary")
+	for _, r := range results {
+		if r.Err != nil {
+			failed++
+			fmt.Fprintf(progress, "  FAILED     %s %s: %s\n", r.BuildpackID, r.BuildpackVersion, strings.ReplaceAll(r.Err.Error(), "\n", " "))
+		} else {
+			fmt.Fprintf(progress, "  SUCCEEDED  %s %s %s\n", r.BuildpackID, r.BuildpackVersion, r.Digest)
+		}
+	}
+	fmt.Printf("%d succeeded, %d failed\n", len(results)-failed, failed)
+
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func writeComposition(p packager.BundleBuildpack, output string) error {
+	entries, err := p.Composition()
+	if err != nil {
+		return fmt.Errorf("unable to read composition of %s\n%w", p.BuildpackPath, err)
+	}
+
+	switch output {
+	case "text":
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, e := range entries {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.ID, orNone(e.Version), orNone(e.URI), orNone(e.Digest))
+		}
+		return w.Flush()
+	case "json":
+		if entries == nil {
+			entries = []packager.CompositionEntry{}
+		}
+		return json.NewEncoder(os.Stdout).Encode(entries)
+	case "name-only":
+		for _, e := range entries {
+			if e.Version != "" {
+				fmt.Printf("%s@%s\n", e.ID, e.Version)
+			} else {
+				fmt.Println(e.ID)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid output %q, must be text, json or name-only", output)
+	}
+}
@@ -37,6 +37,12 @@ func PackageCompileCommand() *cobra.Command {
 				log.Fatal("destination must be set")
 			}
 
+			cacheLocation, err := carton.ResolveCacheLocation(p.CacheLocation)
+			if err != nil {
+				log.Fatal(err)
+			}
+			p.CacheLocation = cacheLocation
+
 			p.Create()
 		},
 	}
@@ -46,6 +52,7 @@ func PackageCompileCommand() *cobra.Command {
 	packageCreateCommand.Flags().BoolVar(&p.IncludeDependencies, "include-dependencies", false, "whether to include dependencies (default: false)")
 	packageCreateCommand.Flags().StringArrayVar(&p.DependencyFilters, "dependency-filter", []string{}, "one or more filters that are applied to exclude dependencies")
 	packageCreateCommand.Flags().BoolVar(&p.StrictDependencyFilters, "strict-filters", false, "require filter to match all data or just some data (default: false)")
+	packageCreateCommand.Flags().StringArrayVar(&p.ExcludeDependencyFilters, "dependency-filter-regex", []string{}, "one or more regular expressions, dependencies whose id or version match any of them are excluded")
 	packageCreateCommand.Flags().StringVar(&p.Source, "source", defaultSource(), "path to build package source directory (default: $PWD)")
 	packageCreateCommand.Flags().StringVar(&p.Version, "version", "", "version to substitute into buildpack.toml/extension.toml")
 	packageCreateCommand.Flags().StringVar(&p.TargetArch, "target-arch", carton.DefaultTargetArch, "target architecture for the package (default: all)")
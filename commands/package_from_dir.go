@@ -0,0 +1,106 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+
+	"github.com/paketo-buildpacks/libpak-tools/internal"
+	"github.com/paketo-buildpacks/libpak-tools/packager"
+)
+
+func PackageFromDirCommand() *cobra.Command {
+	p := packager.NewBundleBuildpack()
+	var path string
+
+	var packageFromDirCmd = &cobra.Command{
+		Use:   "from-dir",
+		Short: "Package a pre-built buildpack directory without compiling it",
+		Run: func(cmd *cobra.Command, args []string) {
+			p.Verbose = verbose
+
+			if path == "" {
+				log.Fatal("path must be set")
+			}
+
+			if p.PlatformAPI != "" {
+				if err := packager.ValidatePlatformAPI(p.PlatformAPI); err != nil {
+					log.Fatal(err)
+				}
+			}
+
+			if err := packager.ValidateFlatten(p.Flatten); err != nil {
+				log.Fatal(err)
+			}
+
+			if p.PullPolicy != "" {
+				if err := packager.ValidatePullPolicy(p.PullPolicy); err != nil {
+					log.Fatal(err)
+				}
+			}
+
+			if p.Sign && !p.Publish {
+				log.Fatal("sign requires publish")
+			}
+
+			if p.OutputFile != "" && p.Publish {
+				log.Fatal("output-file and publish cannot both be set")
+			}
+
+			if err := resolveBundlePaths(&p); err != nil {
+				log.Fatal(err)
+			}
+
+			path, err := absolutePath(path)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if err := p.ExecuteDirectory(path); err != nil {
+				log.Fatal(err)
+			}
+
+			// progress goes to stderr, so the digest can be captured from stdout
+			if p.ResultDigest != "" {
+				fmt.Println(p.ResultDigest)
+			}
+		},
+	}
+
+	packageFromDirCmd.Flags().StringVar(&path, "path", "", "path to a built buildpack directory containing buildpack.toml and bin/")
+	packageFromDirCmd.Flags().StringVar(&p.BuildpackID, "buildpack-id", "", "id of the buildpack (default: the id in buildpack.toml)")
+	packageFromDirCmd.Flags().StringVar(&p.RegistryName, "registry-name", "", "prefix for the registry to publish to (default: your buildpack id)")
+	packageFromDirCmd.Flags().BoolVar(&p.Publish, "publish", false, "publish the buildpack to a buildpack registry (default: false)")
+	packageFromDirCmd.Flags().StringVar(&p.OutputFile, "output-file", "", "write the buildpack to this .cnb file with --format file instead of creating an image")
+	packageFromDirCmd.Flags().StringVar(&p.SummaryFile, "summary-file", "", "write a JSON summary of what was packaged to this file, also when packaging fails")
+	packageFromDirCmd.Flags().Var((*internal.KeyValueFlags)(&p.Labels), "label", "one or more labels to add to the packaged image, e.g. org.opencontainers.image.source=<url>")
+	packageFromDirCmd.Flags().StringVar(&p.PullPolicy, "pull-policy", "", "pull policy passed to pack, always, never or if-not-present (default: $BP_PULL_POLICY or if-not-present)")
+	packageFromDirCmd.Flags().DurationVar(&p.PackageTimeout, "package-timeout", 0, "maximum time pack buildpack package may run, e.g. 30m (default: no limit)")
+	packageFromDirCmd.Flags().StringVar(&p.ContainerEngine, "container-engine", "", "container CLI used to clean up images, docker or podman (default: $BP_CONTAINER_ENGINE or docker)")
+	packageFromDirCmd.Flags().StringArrayVar(&p.AllowedRegistries, "allowed-registry", []string{}, "one or more registry hosts that may be published to (default: any)")
+	packageFromDirCmd.Flags().BoolVar(&p.Sign, "sign", false, "sign the published image with cosign sign, using the key in COSIGN_KEY or keyless signing (default: false)")
+	packageFromDirCmd.Flags().StringVar(&p.CosignBinary, "cosign-binary", "cosign", "path to the cosign binary used to sign the published image")
+	packageFromDirCmd.Flags().StringVar(&p.Flatten, "flatten", "auto", "whether pack flattens the buildpack, auto, true or false (auto does not flatten)")
+	packageFromDirCmd.Flags().StringVar(&p.OS, "os", "", "operating system to package for, linux or windows (default: $BP_OS or linux)")
+	packageFromDirCmd.Flags().BoolVar(&p.StrictArch, "strict-arch", false, "fail, instead of warning, when building locally for another arch than the host without qemu emulation (default: false)")
+	packageFromDirCmd.Flags().StringVar(&p.PlatformAPI, "platform-api", "", "platform API for pack to target, e.g. 0.12, passed to pack as CNB_PLATFORM_API (default: pack's own)")
+
+	return packageFromDirCmd
+}
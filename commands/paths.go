@@ -0,0 +1,56 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/paketo-buildpacks/libpak-tools/carton"
+	"github.com/paketo-buildpacks/libpak-tools/packager"
+)
+
+// resolveBundlePaths defaults the cache location and makes the paths of p absolute against the working directory.
+// pack runs in the buildpack, or a temporary build, directory so relative paths would otherwise be resolved there.
+func resolveBundlePaths(p *packager.BundleBuildpack) error {
+	var err error
+	if p.CacheLocation, err = carton.ResolveCacheLocation(p.CacheLocation); err != nil {
+		return err
+	}
+
+	for _, path := range []*string{&p.CacheLocation, &p.BuildpackPath, &p.ConfigDir, &p.OutputFile, &p.SummaryFile} {
+		if *path, err = absolutePath(*path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// absolutePath returns path made absolute against the working directory, an empty path is returned as is
+func absolutePath(path string) (string, error) {
+	if path == "" || filepath.IsAbs(path) {
+		return path, nil
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve %s\n%w", path, err)
+	}
+
+	return abs, nil
+}
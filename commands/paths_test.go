@@ -0,0 +1,99 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libpak-tools/packager"
+)
+
+func testPaths(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		wd string
+	)
+
+	it.Before(func() {
+		var err error
+		wd, err = filepath.EvalSymlinks(t.TempDir())
+		Expect(err).NotTo(HaveOccurred())
+
+		previous, err := os.Getwd()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.Chdir(wd)).To(Succeed())
+		t.Cleanup(func() { _ = os.Chdir(previous) })
+	})
+
+	it("resolves relative paths against the working directory", func() {
+		p := packager.BundleBuildpack{
+			BuildpackPath: "some-buildpack",
+			ConfigDir:     "./some-buildpack/config",
+			OutputFile:    "out/some-buildpack.cnb",
+			SummaryFile:   "summary.json",
+			CacheLocation: "cache",
+		}
+
+		Expect(resolveBundlePaths(&p)).To(Succeed())
+		Expect(p.BuildpackPath).To(Equal(filepath.Join(wd, "some-buildpack")))
+		Expect(p.ConfigDir).To(Equal(filepath.Join(wd, "some-buildpack", "config")))
+		Expect(p.OutputFile).To(Equal(filepath.Join(wd, "out", "some-buildpack.cnb")))
+		Expect(p.SummaryFile).To(Equal(filepath.Join(wd, "summary.json")))
+		Expect(p.CacheLocation).To(Equal(filepath.Join(wd, "cache")))
+	})
+
+	it("keeps absolute paths", func() {
+		p := packager.BundleBuildpack{
+			BuildpackPath: "/some/buildpack",
+			OutputFile:    "/some/out/some-buildpack.cnb",
+			CacheLocation: "/some/cache",
+		}
+
+		Expect(resolveBundlePaths(&p)).To(Succeed())
+		Expect(p.BuildpackPath).To(Equal("/some/buildpack"))
+		Expect(p.OutputFile).To(Equal("/some/out/some-buildpack.cnb"))
+		Expect(p.CacheLocation).To(Equal("/some/cache"))
+	})
+
+	it("defaults the cache location to dependencies in the working directory", func() {
+		p := packager.BundleBuildpack{}
+
+		Expect(resolveBundlePaths(&p)).To(Succeed())
+		Expect(p.CacheLocation).To(Equal(filepath.Join(wd, "dependencies")))
+	})
+
+	it("leaves unset paths empty", func() {
+		p := packager.BundleBuildpack{}
+
+		Expect(resolveBundlePaths(&p)).To(Succeed())
+		Expect(p.BuildpackPath).To(BeEmpty())
+		Expect(p.OutputFile).To(BeEmpty())
+	})
+
+	it("resolves a buildpack path pattern", func() {
+		p := packager.BundleBuildpack{BuildpackPath: "./buildpacks/*"}
+
+		Expect(resolveBundlePaths(&p)).To(Succeed())
+		Expect(p.BuildpackPath).To(Equal(filepath.Join(wd, "buildpacks", "*")))
+	})
+}
@@ -22,9 +22,21 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// verbose is set by the persistent --verbose flag, it shows the diagnostics of the commands that are executed
+var verbose bool
+
+// offline is set by the persistent --offline flag, it is passed on to the commands as BP_OFFLINE
+var offline bool
+
 var rootCmd = &cobra.Command{
 	Use:   "libpak-tools",
 	Short: "A set of tools for managing Paketo libpak based buildpacks",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if offline {
+			return os.Setenv("BP_OFFLINE", "true")
+		}
+		return nil
+	},
 }
 
 func Execute() {
@@ -35,6 +47,11 @@ func Execute() {
 }
 
 func init() {
+	rootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "fail instead of accessing the network and never pull images, same as BP_OFFLINE")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "show the stderr of git and the container engine, which is discarded by default")
+
 	rootCmd.AddCommand(PackageCommand())
+	rootCmd.AddCommand(BuilderCommand())
 	rootCmd.AddCommand(DependencyCommand())
+	rootCmd.AddCommand(VersionCommand())
 }
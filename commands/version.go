@@ -0,0 +1,70 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+
+	"github.com/spf13/cobra"
+)
+
+// version and commit are set at build time with `-ldflags "-X ..."`
+var (
+	version = "dev"
+	commit  = "unknown"
+)
+
+type versionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"goVersion"`
+}
+
+func VersionCommand() *cobra.Command {
+	var output string
+
+	var versionCmd = &cobra.Command{
+		Use:   "version",
+		Short: "Print the version of libpak-tools",
+		Run: func(cmd *cobra.Command, args []string) {
+			info := versionInfo{
+				Version:   version,
+				Commit:    commit,
+				GoVersion: runtime.Version(),
+			}
+
+			switch output {
+			case "text":
+				fmt.Printf("libpak-tools %s (commit: %s, go: %s)\n", info.Version, info.Commit, info.GoVersion)
+			case "json":
+				if err := json.NewEncoder(os.Stdout).Encode(info); err != nil {
+					log.Fatal(fmt.Errorf("unable to encode version\n%w", err))
+				}
+			default:
+				log.Fatalf("invalid output %q, must be text or json", output)
+			}
+		},
+	}
+
+	versionCmd.Flags().StringVar(&output, "output", "text", "output format, text or json")
+
+	return versionCmd
+}
@@ -3,6 +3,7 @@ module github.com/paketo-buildpacks/libpak-tools
 go 1.23
 
 require (
+	github.com/package-url/packageurl-go v0.1.3
 	github.com/paketo-buildpacks/libpak/v2 v2.0.0-alpha.3.0.20241030145014-4e3b6fe37213
 	github.com/spf13/cobra v1.8.1
 	github.com/stretchr/testify v1.10.0
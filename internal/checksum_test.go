@@ -0,0 +1,57 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package internal_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libpak-tools/internal"
+)
+
+func testFileSHA256(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		path string
+	)
+
+	it.Before(func() {
+		path = filepath.Join(t.TempDir(), "artifact.tgz")
+	})
+
+	it("returns the digest of the file", func() {
+		Expect(os.WriteFile(path, []byte("test-artifact"), 0600)).To(Succeed())
+
+		Expect(internal.FileSHA256(path)).To(Equal("a5db9b186b4b28674910702a72ba352b9e71cd699e8e186b4b7c931412edd5f3"))
+	})
+
+	it("returns the digest of an empty file", func() {
+		Expect(os.WriteFile(path, []byte{}, 0600)).To(Succeed())
+
+		Expect(internal.FileSHA256(path)).To(Equal("e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"))
+	})
+
+	it("fails when the file does not exist", func() {
+		_, err := internal.FileSHA256(path)
+		Expect(err).To(MatchError(ContainSubstring("unable to open")))
+	})
+}
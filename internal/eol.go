@@ -1,27 +1,103 @@
 package internal
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/Masterminds/semver/v3"
 )
 
-const eolBaseURL = "https://endoflife.date/api"
+const (
+	eolBaseURL = "https://endoflife.date/api"
+
+	// eolAttempts is the number of times the release cycles are fetched before giving up
+	eolAttempts = 3
+
+	// eolTimeout bounds each request, so that a stalled request fails and is retried
+	eolTimeout = 30 * time.Second
+
+	// eolRetryBackoff is the wait before the second attempt, it doubles after each further attempt
+	eolRetryBackoff = 250 * time.Millisecond
+)
 
 func GetEolDate(eolID, version string) (string, error) {
-	cycleList, err := getProjectCycleList(eolID)
+	client, err := NewEolHTTPClient()
+	if err != nil {
+		return "", err
+	}
+
+	return GetEolDateWithClient(client, eolID, version)
+}
+
+// GetEolDateWithClient is GetEolDate but fetches the release cycles with client
+func GetEolDateWithClient(client *http.Client, eolID, version string) (string, error) {
+	return GetEolDateWithRetries(client, eolID, version, eolAttempts)
+}
+
+// GetEolDateWithRetries is GetEolDateWithClient but makes up to attempts attempts to fetch the release cycles
+func GetEolDateWithRetries(client *http.Client, eolID, version string, attempts int) (string, error) {
+	cycleList, err := getProjectCycleList(client, eolID, attempts)
+	if err != nil {
+		return "", fmt.Errorf("could not fetch cycle list: %w", err)
+	}
+
+	cycle, err := selectCycle(version, cycleList)
+	if err != nil {
+		return "", fmt.Errorf("could not find a release cycle: %w", err)
+	}
+
+	return formatEol(cycle)
+}
+
+// GetEolDateWithCache is GetEolDate but looks the release cycles up in the JSON cache file at cachePath first. The
+// cycles are only fetched when the cache has no cycle for the version, and then the cache is updated. A missing cache
+// file is treated as empty.
+func GetEolDateWithCache(eolID, version, cachePath string) (string, error) {
+	if cachePath == "" {
+		return GetEolDate(eolID, version)
+	}
+
+	cache, err := readEolCache(cachePath)
+	if err != nil {
+		return "", err
+	}
+
+	if cycles, found := cache[eolID]; found {
+		if cycle, err := selectCycle(version, cycles); err == nil {
+			return formatEol(cycle)
+		}
+	}
+
+	client, err := NewEolHTTPClient()
+	if err != nil {
+		return "", err
+	}
+
+	cycleList, err := getProjectCycleList(client, eolID, eolAttempts)
 	if err != nil {
 		return "", fmt.Errorf("could not fetch cycle list: %w", err)
 	}
 
+	cache[eolID] = cycleList
+	if err := writeEolCache(cachePath, cache); err != nil {
+		return "", err
+	}
+
 	cycle, err := selectCycle(version, cycleList)
 	if err != nil {
 		return "", fmt.Errorf("could not find a release cycle: %w", err)
 	}
 
+	return formatEol(cycle)
+}
+
+func formatEol(cycle *cycle) (string, error) {
 	if cycle.EOL == "" {
 		return "", nil
 	}
@@ -34,6 +110,37 @@ func GetEolDate(eolID, version string) (string, error) {
 	return eol.Format(time.RFC3339), nil
 }
 
+func readEolCache(path string) (map[string]cycleList, error) {
+	cache := map[string]cycleList{}
+
+	c, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cache, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read eol cache %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(c, &cache); err != nil {
+		return nil, fmt.Errorf("could not decode eol cache %s: %w", path, err)
+	}
+
+	return cache, nil
+}
+
+func writeEolCache(path string, cache map[string]cycleList) error {
+	c, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode eol cache: %w", err)
+	}
+
+	// #nosec G306 - the cache only holds public release cycle data
+	if err := os.WriteFile(path, c, 0644); err != nil {
+		return fmt.Errorf("could not write eol cache %s: %w", path, err)
+	}
+
+	return nil
+}
+
 func selectCycle(version string, cycles cycleList) (*cycle, error) {
 	versionParsed, err := semver.NewVersion(version)
 	if err != nil {
@@ -51,30 +158,99 @@ func selectCycle(version string, cycles cycleList) (*cycle, error) {
 	return nil, fmt.Errorf("no release cycle found for the version %s", version)
 }
 
-func getProjectCycleList(id string) (cycleList, error) {
-	res, err := http.Get(fmt.Sprintf("%s/%s.json", eolBaseURL, id))
+// NewEolHTTPClient returns the client used to look up EOL dates. It uses HTTPS_PROXY, HTTP_PROXY and NO_PROXY like
+// the default client and, when BP_CA_CERT is set, also trusts the certificates in the PEM file it points at. Each
+// request times out after 30s.
+func NewEolHTTPClient() (*http.Client, error) {
+	caCert, found := os.LookupEnv("BP_CA_CERT")
+	if !found || caCert == "" {
+		return &http.Client{Timeout: eolTimeout}, nil
+	}
+
+	c, err := os.ReadFile(caCert)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("could not read BP_CA_CERT %s: %w", caCert, err)
+	}
+
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	if !pool.AppendCertsFromPEM(c) {
+		return nil, fmt.Errorf("no certificates found in BP_CA_CERT %s", caCert)
+	}
+
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if ok {
+		transport = transport.Clone()
+	} else {
+		transport = &http.Transport{Proxy: http.ProxyFromEnvironment}
+	}
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+
+	return &http.Client{Transport: transport, Timeout: eolTimeout}, nil
+}
+
+// getProjectCycleList fetches the release cycles of a project. Network errors, 429 and 5xx responses are retried with
+// an exponential backoff, up to attempts attempts in total.
+func getProjectCycleList(client *http.Client, id string, attempts int) (cycleList, error) {
+	if Offline() {
+		return nil, ErrOffline
+	}
+
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	backoff := eolRetryBackoff
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		var cycles cycleList
+		var retryable bool
+		cycles, retryable, err = fetchProjectCycleList(client, id)
+		if err == nil {
+			return cycles, nil
+		} else if !retryable {
+			return nil, err
+		}
+	}
+
+	return nil, fmt.Errorf("failed to fetch release cycles after %d attempts: %w", attempts, err)
+}
+
+// fetchProjectCycleList makes a single request for the release cycles of a project, and reports whether a failure may
+// succeed if it is retried
+func fetchProjectCycleList(client *http.Client, id string) (cycleList, bool, error) {
+	res, err := client.Get(fmt.Sprintf("%s/%s.json", eolBaseURL, id))
+	if err != nil {
+		return nil, true, err
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to fetch release cycles, status: %d", res.StatusCode)
+		retryable := res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError
+		return nil, retryable, fmt.Errorf("failed to fetch release cycles, status: %d", res.StatusCode)
 	}
 
 	cycles := cycleList{}
 	if err := json.NewDecoder(res.Body).Decode(&cycles); err != nil {
-		return nil, err
+		return nil, false, err
 	}
 
-	return cycles, nil
+	return cycles, false, nil
 }
 
 type cycleList []*cycle
 
 type cycle struct {
-	Cycle string
-	EOL   string
+	Cycle string `json:"cycle"`
+	EOL   string `json:"eol"`
 }
 
 func (c *cycle) UnmarshalJSON(data []byte) error {
@@ -1,8 +1,16 @@
 package internal_test
 
 import (
+	"encoding/pem"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/buildpacks/libcnb/v2/mocks"
 	"github.com/jarcoal/httpmock"
@@ -82,4 +90,226 @@ func testGetEolDate(t *testing.T, context spec.G, it spec.S) {
 			Expect(eolDate).To(Equal(""))
 		})
 	})
+
+	context("with a cache", func() {
+		var cachePath string
+
+		it.Before(func() {
+			cachePath = filepath.Join(t.TempDir(), "eol-cache.json")
+			httpmock.RegisterResponder(http.MethodGet, "https://endoflife.date/api/foo.json", httpmock.NewBytesResponder(200, []byte(`[
+	{ "cycle": "10.1", "eol": false },
+	{ "cycle": "10.0", "eol": "2026-12-31" }
+]`)))
+		})
+
+		it("treats a missing cache as empty and writes the fetched cycles to it", func() {
+			eolDate, err := internal.GetEolDateWithCache("foo", "10.0.1", cachePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(eolDate).To(Equal("2026-12-31T00:00:00Z"))
+
+			Expect(httpmock.GetTotalCallCount()).To(Equal(1))
+			Expect(os.ReadFile(cachePath)).To(MatchJSON(`{
+				"foo": [
+					{ "cycle": "10.1", "eol": "" },
+					{ "cycle": "10.0", "eol": "2026-12-31" }
+				]
+			}`))
+		})
+
+		it("uses the cache without fetching", func() {
+			Expect(os.WriteFile(cachePath, []byte(`{ "foo": [ { "cycle": "10.0", "eol": "2030-01-01" } ] }`), 0600)).To(Succeed())
+
+			eolDate, err := internal.GetEolDateWithCache("foo", "10.0.1", cachePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(eolDate).To(Equal("2030-01-01T00:00:00Z"))
+			Expect(httpmock.GetTotalCallCount()).To(Equal(0))
+		})
+
+		it("fetches when the cache has no cycle for the version", func() {
+			Expect(os.WriteFile(cachePath, []byte(`{ "foo": [ { "cycle": "9", "eol": "2023-12-31" } ] }`), 0600)).To(Succeed())
+
+			eolDate, err := internal.GetEolDateWithCache("foo", "10.1.0", cachePath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(eolDate).To(Equal(""))
+			Expect(httpmock.GetTotalCallCount()).To(Equal(1))
+		})
+	})
+
+	context("with a client", func() {
+		it("fetches the release cycles with the client", func() {
+			client := &http.Client{}
+			httpmock.ActivateNonDefault(client)
+			httpmock.RegisterResponder(http.MethodGet, "https://endoflife.date/api/foo.json", httpmock.NewBytesResponder(200, []byte(`[
+	{ "cycle": "10.0", "eol": "2026-12-31" }
+]`)))
+
+			eolDate, err := internal.GetEolDateWithRetries(client, "foo", "10.0.1", 1)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(eolDate).To(Equal("2026-12-31T00:00:00Z"))
+		})
+	})
+
+	context("offline", func() {
+		it("fails without making a request", func() {
+			t.Setenv("BP_OFFLINE", "true")
+
+			client := &http.Client{}
+			httpmock.ActivateNonDefault(client)
+
+			_, err := internal.GetEolDateWithClient(client, "foo", "10.0.1")
+			Expect(err).To(MatchError(internal.ErrOffline))
+			Expect(httpmock.GetTotalCallCount()).To(Equal(0))
+		})
+	})
+
+	context("BP_CA_CERT", func() {
+		var server *httptest.Server
+
+		it.Before(func() {
+			httpmock.DeactivateAndReset()
+			server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+		})
+
+		it.After(func() {
+			server.Close()
+		})
+
+		it("uses the default transport with a timeout when it is not set", func() {
+			Expect(os.Unsetenv("BP_CA_CERT")).To(Succeed())
+
+			client, err := internal.NewEolHTTPClient()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(client.Transport).To(BeNil())
+			Expect(client.Timeout).To(Equal(30 * time.Second))
+		})
+
+		it("trusts the certificates in the file", func() {
+			caCert := filepath.Join(t.TempDir(), "ca.pem")
+			Expect(os.WriteFile(caCert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600)).To(Succeed())
+			t.Setenv("BP_CA_CERT", caCert)
+
+			client, err := internal.NewEolHTTPClient()
+			Expect(err).NotTo(HaveOccurred())
+
+			res, err := client.Get(server.URL)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Body.Close()).To(Succeed())
+			Expect(res.StatusCode).To(Equal(http.StatusOK))
+			Expect(client.Timeout).To(Equal(30 * time.Second))
+		})
+
+		it("fails if the file has no certificates", func() {
+			caCert := filepath.Join(t.TempDir(), "ca.pem")
+			Expect(os.WriteFile(caCert, []byte("not a certificate"), 0600)).To(Succeed())
+			t.Setenv("BP_CA_CERT", caCert)
+
+			_, err := internal.NewEolHTTPClient()
+			Expect(err).To(MatchError(fmt.Sprintf("no certificates found in BP_CA_CERT %s", caCert)))
+		})
+
+		it("fails if the file does not exist", func() {
+			t.Setenv("BP_CA_CERT", filepath.Join(t.TempDir(), "missing.pem"))
+
+			_, err := internal.NewEolHTTPClient()
+			Expect(err).To(MatchError(ContainSubstring("could not read BP_CA_CERT")))
+		})
+	})
+
+	context("retries", func() {
+		var (
+			client   *http.Client
+			mutex    sync.Mutex
+			release  chan struct{}
+			requests int
+			server   *httptest.Server
+			stalls   int
+			statuses []int
+		)
+
+		it.Before(func() {
+			httpmock.DeactivateAndReset()
+
+			requests = 0
+			stalls = 0
+			release = make(chan struct{})
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mutex.Lock()
+				stall := requests < stalls
+				status := http.StatusOK
+				if !stall && requests < len(statuses) {
+					status = statuses[requests]
+				}
+				requests++
+				mutex.Unlock()
+
+				if stall {
+					<-release
+					return
+				}
+
+				w.WriteHeader(status)
+				if status == http.StatusOK {
+					_, _ = w.Write([]byte(`[ { "cycle": "10.0", "eol": "2026-12-31" } ]`))
+				}
+			}))
+
+			target, err := url.Parse(server.URL)
+			Expect(err).NotTo(HaveOccurred())
+			client = &http.Client{Transport: rewriteTransport{target: target}}
+		})
+
+		it.After(func() {
+			close(release)
+			server.Close()
+		})
+
+		it("retries requests that time out", func() {
+			stalls = 1
+			client.Timeout = 100 * time.Millisecond
+
+			eolDate, err := internal.GetEolDateWithRetries(client, "foo", "10.0.1", 2)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(eolDate).To(Equal("2026-12-31T00:00:00Z"))
+			Expect(requests).To(Equal(2))
+		})
+
+		it("retries 503 responses", func() {
+			statuses = []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}
+
+			eolDate, err := internal.GetEolDateWithClient(client, "foo", "10.0.1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(eolDate).To(Equal("2026-12-31T00:00:00Z"))
+			Expect(requests).To(Equal(3))
+		})
+
+		it("fails once the attempts are exhausted", func() {
+			statuses = []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}
+
+			_, err := internal.GetEolDateWithRetries(client, "foo", "10.0.1", 1)
+			Expect(err).To(MatchError("could not fetch cycle list: failed to fetch release cycles after 1 attempts: failed to fetch release cycles, status: 503"))
+			Expect(requests).To(Equal(1))
+		})
+
+		it("does not retry other failures", func() {
+			statuses = []int{http.StatusNotFound}
+
+			_, err := internal.GetEolDateWithClient(client, "foo", "10.0.1")
+			Expect(err).To(MatchError("could not fetch cycle list: failed to fetch release cycles, status: 404"))
+			Expect(requests).To(Equal(1))
+		})
+	})
+}
+
+// rewriteTransport sends every request to target, so that requests to endoflife.date reach a test server
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (r rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = r.target.Scheme
+	req.URL.Host = r.target.Host
+	return (&http.Transport{}).RoundTrip(req)
 }
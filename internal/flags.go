@@ -0,0 +1,57 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// KeyValueFlags is a repeatable flag of key=value entries, an entry without a key or value is rejected when it is set
+type KeyValueFlags []string
+
+func (k *KeyValueFlags) String() string {
+	if len(*k) == 0 {
+		return ""
+	}
+
+	return "[" + strings.Join(*k, ",") + "]"
+}
+
+func (k *KeyValueFlags) Set(value string) error {
+	if key, v, found := strings.Cut(value, "="); !found || key == "" || v == "" {
+		return fmt.Errorf("%q must be key=value", value)
+	}
+
+	*k = append(*k, value)
+	return nil
+}
+
+func (k *KeyValueFlags) Type() string {
+	return "key=value"
+}
+
+// Map returns the entries by key, when a key is set more than once the last value is used
+func (k *KeyValueFlags) Map() map[string]string {
+	m := map[string]string{}
+	for _, entry := range *k {
+		key, value, _ := strings.Cut(entry, "=")
+		m[key] = value
+	}
+
+	return m
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package internal_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libpak-tools/internal"
+)
+
+func testKeyValueFlags(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		flags internal.KeyValueFlags
+	)
+
+	it.Before(func() {
+		flags = internal.KeyValueFlags{}
+	})
+
+	it("collects entries by key", func() {
+		Expect(flags.Set("amd64=https://localhost/amd64")).To(Succeed())
+		Expect(flags.Set("arm64=https://localhost/arm64?a=b")).To(Succeed())
+
+		Expect(flags.Map()).To(Equal(map[string]string{
+			"amd64": "https://localhost/amd64",
+			"arm64": "https://localhost/arm64?a=b",
+		}))
+		Expect(flags.String()).To(Equal("[amd64=https://localhost/amd64,arm64=https://localhost/arm64?a=b]"))
+	})
+
+	it("uses the last value of a repeated key", func() {
+		Expect(flags.Set("amd64=first")).To(Succeed())
+		Expect(flags.Set("amd64=second")).To(Succeed())
+
+		Expect(flags.Map()).To(Equal(map[string]string{"amd64": "second"}))
+	})
+
+	it("returns an empty map when nothing is set", func() {
+		Expect(flags.Map()).To(BeEmpty())
+		Expect(flags.String()).To(BeEmpty())
+	})
+
+	context("malformed entries", func() {
+		it("rejects an entry without =", func() {
+			Expect(flags.Set("amd64")).To(MatchError(`"amd64" must be key=value`))
+		})
+
+		it("rejects an entry without a key", func() {
+			Expect(flags.Set("=value")).To(MatchError(`"=value" must be key=value`))
+		})
+
+		it("rejects an entry without a value", func() {
+			Expect(flags.Set("amd64=")).To(MatchError(`"amd64=" must be key=value`))
+		})
+
+		it("does not keep rejected entries", func() {
+			Expect(flags.Set("amd64")).NotTo(Succeed())
+			Expect(flags).To(BeEmpty())
+		})
+	})
+}
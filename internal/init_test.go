@@ -24,6 +24,10 @@ import (
 
 func TestUnit(t *testing.T) {
 	suite := spec.New("libpak-tools/internal", spec.Report(report.Terminal{}))
+	suite("CheckURI", testCheckURI)
 	suite("EOL", testGetEolDate)
+	suite("FileSHA256", testFileSHA256)
+	suite("KeyValueFlags", testKeyValueFlags)
+	suite("TOML", testTOML)
 	suite.Run(t)
 }
@@ -0,0 +1,31 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package internal
+
+import (
+	"errors"
+
+	"github.com/paketo-buildpacks/libpak/v2/sherpa"
+)
+
+// ErrOffline is returned instead of accessing the network when offline mode is enabled
+var ErrOffline = errors.New("network access is disabled because BP_OFFLINE is set")
+
+// Offline returns true if offline mode is enabled with BP_OFFLINE, which the global --offline flag sets
+func Offline() bool {
+	return sherpa.ResolveBool("BP_OFFLINE")
+}
@@ -0,0 +1,321 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// TOMLOptions configures how UpdateTOMLFile writes a file.
+type TOMLOptions struct {
+	// Indent is the number of spaces nested tables are indented with, when zero the encoder default of two spaces is
+	// used
+	Indent int
+
+	// Backup copies the file to <path>.bak before it is written. The copy is removed once the write succeeds and left in
+	// place if it fails.
+	Backup bool
+
+	// DryRun applies the update without writing the file
+	DryRun bool
+
+	// FailOnNoChange returns ErrNoChange when the updated file would be byte-identical to the original
+	FailOnNoChange bool
+}
+
+// ErrNoChange is returned by UpdateTOMLFile when TOMLOptions.FailOnNoChange is set and the file is unchanged
+var ErrNoChange = errors.New("no changes were made")
+
+// UpdateTOMLFile decodes the TOML file at path, applies f to it and writes it back. Leading comments, like license
+// headers, are preserved but inline comments are lost.
+func UpdateTOMLFile(path string, options TOMLOptions, f func(md map[string]interface{}) error) error {
+	b, err := RenderTOMLFile(path, options, f)
+	if err != nil {
+		return err
+	}
+
+	return WriteTOMLFile(path, b, options)
+}
+
+// RenderTOMLFile decodes the TOML file at path, applies f to it and returns the encoded result without writing it.
+func RenderTOMLFile(path string, options TOMLOptions, f func(md map[string]interface{}) error) ([]byte, error) {
+	c, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s\n%w", path, err)
+	}
+
+	return RenderTOML(c, path, options, f)
+}
+
+// RenderTOML decodes the TOML document c, applies f to it and returns the encoded result. path is only used in error
+// messages. The result uses the line ending that most lines of c end with, and ends with as many newlines as c.
+func RenderTOML(c []byte, path string, options TOMLOptions, f func(md map[string]interface{}) error) ([]byte, error) {
+	// save any leading comments, this is to preserve license headers
+	// inline comments will be lost
+	comments := []byte{}
+	for i, line := range bytes.SplitAfter(c, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte("#")) || (i > 0 && len(bytes.TrimSpace(line)) == 0) {
+			comments = append(comments, line...)
+		} else {
+			break // stop on first comment
+		}
+	}
+
+	md := make(map[string]interface{})
+	if err := toml.Unmarshal(c, &md); err != nil {
+		return nil, fmt.Errorf("unable to decode md %s\n%w", path, err)
+	}
+
+	if err := f(md); err != nil {
+		return nil, err
+	}
+
+	b, err := MarshalTOML(md, options)
+	if err != nil {
+		return nil, fmt.Errorf("unable to encode md %s\n%w", path, err)
+	}
+
+	b = append(bytes.ReplaceAll(comments, []byte("\r\n"), []byte("\n")), b...)
+
+	// the encoder always ends with a single newline, keep the trailing newlines of c instead to avoid churn
+	if trimmed := bytes.TrimRight(b, "\n"); len(trimmed) > 0 {
+		b = append(trimmed, bytes.Repeat([]byte("\n"), trailingNewlines(c))...)
+	}
+
+	// the encoder writes LF, so a file that mostly uses CRLF, like a Windows checkout, is converted back to CRLF
+	if isCRLF(c) {
+		b = bytes.ReplaceAll(b, []byte("\n"), []byte("\r\n"))
+	}
+
+	if options.FailOnNoChange && bytes.Equal(c, b) {
+		return nil, fmt.Errorf("unable to update %s\n%w", path, ErrNoChange)
+	}
+
+	return b, nil
+}
+
+// trailingNewlines returns the number of newlines, LF or CRLF, that c ends with
+func trailingNewlines(c []byte) int {
+	c = bytes.ReplaceAll(c, []byte("\r\n"), []byte("\n"))
+	return len(c) - len(bytes.TrimRight(c, "\n"))
+}
+
+// isCRLF returns true if more lines of c end with CRLF than with LF alone
+func isCRLF(c []byte) bool {
+	crlf := bytes.Count(c, []byte("\r\n"))
+	return crlf > bytes.Count(c, []byte("\n"))-crlf
+}
+
+// WriteTOMLFile writes the output of RenderTOMLFile to path, honoring the DryRun and Backup options.
+func WriteTOMLFile(path string, b []byte, options TOMLOptions) error {
+	if options.DryRun {
+		return nil
+	}
+
+	backup := fmt.Sprintf("%s.bak", path)
+	if options.Backup {
+		if err := copyFile(path, backup); err != nil {
+			return fmt.Errorf("unable to back up %s\n%w", path, err)
+		}
+	}
+
+	if err := writeFileAtomic(path, b); err != nil {
+		return fmt.Errorf("unable to write %s\n%w", path, err)
+	}
+
+	if options.Backup {
+		if err := os.Remove(backup); err != nil {
+			return fmt.Errorf("unable to remove %s\n%w", backup, err)
+		}
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes c to a temporary file next to path and renames it into place, so that path is never left
+// partially written. The permissions of the existing file are kept.
+func writeFileAtomic(path string, c []byte) error {
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fmt.Errorf("unable to resolve %s\n%w", path, err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("unable to stat %s\n%w", path, err)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), fmt.Sprintf(".%s-*", filepath.Base(path)))
+	if err != nil {
+		return fmt.Errorf("unable to create temporary file\n%w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(c); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("unable to write %s\n%w", f.Name(), err)
+	}
+
+	if err := f.Chmod(info.Mode().Perm()); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("unable to chmod %s\n%w", f.Name(), err)
+	}
+
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("unable to sync %s\n%w", f.Name(), err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("unable to close %s\n%w", f.Name(), err)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("unable to rename %s to %s\n%w", f.Name(), path, err)
+	}
+
+	return nil
+}
+
+// copyFile copies source to destination with the same permissions
+func copyFile(source string, destination string) error {
+	info, err := os.Stat(source)
+	if err != nil {
+		return fmt.Errorf("unable to stat %s\n%w", source, err)
+	}
+
+	c, err := os.ReadFile(source)
+	if err != nil {
+		return fmt.Errorf("unable to read %s\n%w", source, err)
+	}
+
+	if err := os.WriteFile(destination, c, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("unable to write %s\n%w", destination, err)
+	}
+
+	// the umask may have been applied when the file was created
+	if err := os.Chmod(destination, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("unable to chmod %s\n%w", destination, err)
+	}
+
+	return nil
+}
+
+// TableArray returns v as an array of tables. The decoder returns an array of tables written with [[...]] headers as
+// []map[string]interface{}, but an inline array of tables as []interface{}, so both are accepted. It returns false if
+// v, or any element of it, is not a table.
+func TableArray(v interface{}) ([]map[string]interface{}, bool) {
+	switch t := v.(type) {
+	case []map[string]interface{}:
+		return t, true
+	case []interface{}:
+		tables := make([]map[string]interface{}, 0, len(t))
+		for _, e := range t {
+			table, ok := e.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			tables = append(tables, table)
+		}
+		return tables, true
+	default:
+		return nil, false
+	}
+}
+
+// MarshalTOML encodes v as TOML using options
+func MarshalTOML(v interface{}, options TOMLOptions) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	encoder := toml.NewEncoder(buf)
+	if options.Indent > 0 {
+		encoder.Indent = strings.Repeat(" ", options.Indent)
+	}
+
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return orderDependencyKeys(buf.Bytes()), nil
+}
+
+// dependencyKeyOrder is the order keys are written in a dependency, as in hand-authored Paketo build modules. Other
+// keys follow in the order they were encoded.
+var dependencyKeyOrder = []string{
+	"id", "name", "version", "uri", "sha256", "checksum", "stacks", "purl", "purls", "cpes", "source", "source-sha256",
+	"source-checksum", "deprecation_date",
+}
+
+// orderDependencyKeys rewrites the keys of each [[metadata.dependencies]] entry, which the encoder sorts
+// alphabetically, in dependencyKeyOrder. Only entries with an id are reordered, so nested tables like licenses are
+// left as they are. Every key is on its own line because the encoder writes arrays and strings inline.
+func orderDependencyKeys(b []byte) []byte {
+	lines := bytes.SplitAfter(b, []byte("\n"))
+
+	for i := 0; i < len(lines); i++ {
+		header := bytes.TrimSpace(lines[i])
+		if !bytes.HasPrefix(header, []byte("[[metadata.dependencies")) || !bytes.HasSuffix(header, []byte("]]")) {
+			continue
+		}
+
+		start := i + 1
+		end := start
+		hasID := false
+		for ; end < len(lines); end++ {
+			line := bytes.TrimSpace(lines[end])
+			if len(line) == 0 || line[0] == '[' {
+				break
+			}
+
+			if dependencyKey(line) == "id" {
+				hasID = true
+			}
+		}
+
+		if hasID {
+			slices.SortStableFunc(lines[start:end], func(a, b []byte) int {
+				return dependencyKeyRank(a) - dependencyKeyRank(b)
+			})
+		}
+
+		i = end - 1
+	}
+
+	return bytes.Join(lines, nil)
+}
+
+// dependencyKey returns the key of a `key = value` line
+func dependencyKey(line []byte) string {
+	key, _, _ := bytes.Cut(bytes.TrimSpace(line), []byte("="))
+	return strings.Trim(string(bytes.TrimSpace(key)), `"`)
+}
+
+// dependencyKeyRank returns the position of the key of line in dependencyKeyOrder, keys that are not listed rank last
+func dependencyKeyRank(line []byte) int {
+	if i := slices.Index(dependencyKeyOrder, dependencyKey(line)); i >= 0 {
+		return i
+	}
+
+	return len(dependencyKeyOrder)
+}
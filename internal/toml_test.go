@@ -0,0 +1,305 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package internal_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libpak-tools/internal"
+)
+
+func testTOML(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		path string
+	)
+
+	it.Before(func() {
+		path = filepath.Join(t.TempDir(), "buildpack.toml")
+		Expect(os.WriteFile(path, []byte(`# Copyright header
+
+[buildpack]
+id = "some-id"
+`), 0600)).To(Succeed())
+	})
+
+	it("preserves leading comments", func() {
+		Expect(internal.UpdateTOMLFile(path, internal.TOMLOptions{}, func(md map[string]interface{}) error {
+			md["api"] = "0.7"
+			return nil
+		})).To(Succeed())
+
+		Expect(os.ReadFile(path)).To(Equal([]byte(`# Copyright header
+
+api = "0.7"
+
+[buildpack]
+  id = "some-id"
+`)))
+	})
+
+	it("indents nested tables with the chosen width", func() {
+		Expect(internal.UpdateTOMLFile(path, internal.TOMLOptions{Indent: 4}, func(md map[string]interface{}) error {
+			return nil
+		})).To(Succeed())
+
+		Expect(os.ReadFile(path)).To(Equal([]byte(`# Copyright header
+
+[buildpack]
+    id = "some-id"
+`)))
+	})
+
+	it("does not write the file when the update fails", func() {
+		Expect(internal.UpdateTOMLFile(path, internal.TOMLOptions{}, func(md map[string]interface{}) error {
+			md["api"] = "0.7"
+			return fmt.Errorf("test-error")
+		})).To(MatchError("test-error"))
+
+		Expect(os.ReadFile(path)).NotTo(ContainSubstring("api"))
+	})
+
+	context("backup", func() {
+		it("removes the backup after a successful write", func() {
+			Expect(internal.UpdateTOMLFile(path, internal.TOMLOptions{Backup: true}, func(md map[string]interface{}) error {
+				md["api"] = "0.7"
+				return nil
+			})).To(Succeed())
+
+			Expect(path + ".bak").NotTo(BeAnExistingFile())
+			Expect(os.ReadFile(path)).To(ContainSubstring(`api = "0.7"`))
+		})
+
+		it("leaves the file untouched when the backup cannot be written", func() {
+			if os.Geteuid() == 0 {
+				t.Skip("root can write to read-only directories")
+			}
+
+			Expect(os.Chmod(filepath.Dir(path), 0500)).To(Succeed())
+			t.Cleanup(func() { _ = os.Chmod(filepath.Dir(path), 0700) })
+
+			Expect(internal.UpdateTOMLFile(path, internal.TOMLOptions{Backup: true}, func(md map[string]interface{}) error {
+				md["api"] = "0.7"
+				return nil
+			})).To(MatchError(ContainSubstring("unable to back up")))
+
+			Expect(os.ReadFile(path)).NotTo(ContainSubstring("api"))
+		})
+	})
+
+	context("atomic write", func() {
+		it("keeps the permissions of the original file", func() {
+			Expect(os.Chmod(path, 0640)).To(Succeed())
+
+			Expect(internal.UpdateTOMLFile(path, internal.TOMLOptions{}, func(md map[string]interface{}) error {
+				md["api"] = "0.7"
+				return nil
+			})).To(Succeed())
+
+			info, err := os.Stat(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0640)))
+		})
+
+		it("replaces the file without leaving temporary files behind", func() {
+			Expect(internal.UpdateTOMLFile(path, internal.TOMLOptions{}, func(md map[string]interface{}) error {
+				md["api"] = "0.7"
+				return nil
+			})).To(Succeed())
+
+			entries, err := os.ReadDir(filepath.Dir(path))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(1))
+			Expect(entries[0].Name()).To(Equal("buildpack.toml"))
+		})
+
+		it("writes through a symlink", func() {
+			link := filepath.Join(t.TempDir(), "link.toml")
+			Expect(os.Symlink(path, link)).To(Succeed())
+
+			Expect(internal.UpdateTOMLFile(link, internal.TOMLOptions{}, func(md map[string]interface{}) error {
+				md["api"] = "0.7"
+				return nil
+			})).To(Succeed())
+
+			info, err := os.Lstat(link)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode() & os.ModeSymlink).NotTo(BeZero())
+			Expect(os.ReadFile(path)).To(ContainSubstring(`api = "0.7"`))
+		})
+	})
+
+	context("fail on no change", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(path, []byte(`# Copyright header
+
+[buildpack]
+  id = "some-id"
+`), 0600)).To(Succeed())
+		})
+
+		it("returns ErrNoChange when the file is unchanged", func() {
+			err := internal.UpdateTOMLFile(path, internal.TOMLOptions{FailOnNoChange: true}, func(md map[string]interface{}) error {
+				return nil
+			})
+			Expect(err).To(MatchError(internal.ErrNoChange))
+		})
+
+		it("succeeds when the file is changed", func() {
+			Expect(internal.UpdateTOMLFile(path, internal.TOMLOptions{FailOnNoChange: true}, func(md map[string]interface{}) error {
+				md["api"] = "0.7"
+				return nil
+			})).To(Succeed())
+
+			Expect(os.ReadFile(path)).To(ContainSubstring(`api = "0.7"`))
+		})
+	})
+
+	context("line endings", func() {
+		it("writes CRLF when most lines end with CRLF", func() {
+			Expect(os.WriteFile(path, []byte("# Copyright header\r\n\r\n[buildpack]\r\nid = \"some-id\"\r\n"), 0600)).To(Succeed())
+
+			Expect(internal.UpdateTOMLFile(path, internal.TOMLOptions{}, func(md map[string]interface{}) error {
+				md["api"] = "0.7"
+				return nil
+			})).To(Succeed())
+
+			Expect(os.ReadFile(path)).To(Equal([]byte("# Copyright header\r\n\r\napi = \"0.7\"\r\n\r\n[buildpack]\r\n  id = \"some-id\"\r\n")))
+		})
+
+		it("writes LF when most lines end with LF", func() {
+			Expect(os.WriteFile(path, []byte("# Copyright header\r\n\n[buildpack]\nid = \"some-id\"\n"), 0600)).To(Succeed())
+
+			Expect(internal.UpdateTOMLFile(path, internal.TOMLOptions{}, func(md map[string]interface{}) error {
+				return nil
+			})).To(Succeed())
+
+			Expect(os.ReadFile(path)).To(Equal([]byte("# Copyright header\n\n[buildpack]\n  id = \"some-id\"\n")))
+		})
+	})
+
+	context("trailing newlines", func() {
+		for _, c := range []struct {
+			name     string
+			trailing string
+		}{
+			{"no trailing newline", ""},
+			{"one trailing newline", "\n"},
+			{"several trailing newlines", "\n\n\n"},
+		} {
+			it(fmt.Sprintf("keeps %s", c.name), func() {
+				Expect(os.WriteFile(path, []byte("[buildpack]\nid = \"some-id\""+c.trailing), 0600)).To(Succeed())
+
+				Expect(internal.UpdateTOMLFile(path, internal.TOMLOptions{}, func(md map[string]interface{}) error {
+					delete(md["buildpack"].(map[string]interface{}), "id")
+					md["buildpack"].(map[string]interface{})["name"] = "Some Name"
+					return nil
+				})).To(Succeed())
+
+				Expect(os.ReadFile(path)).To(Equal([]byte("[buildpack]\n  name = \"Some Name\"" + c.trailing)))
+			})
+		}
+	})
+
+	context("dependency key order", func() {
+		it.Before(func() {
+			Expect(os.WriteFile(path, []byte(`[metadata]
+
+  [[metadata.dependencies]]
+    id = "some-dep"
+    name = "Some Dependency"
+    version = "1.0.0"
+    uri = "https://localhost/some-dep-1.0.0.tgz"
+    sha256 = "some-sha256"
+    stacks = ["*"]
+    purl = "pkg:generic/some-dep@1.0.0"
+    cpes = ["cpe:2.3:a:some:dep:1.0.0:*:*:*:*:*:*:*"]
+    source = "https://localhost/some-dep-1.0.0-src.tgz"
+    source-sha256 = "some-source-sha256"
+    deprecation_date = "2030-01-01T00:00:00Z"
+    arch = "amd64"
+    strip-components = 1
+
+    [[metadata.dependencies.licenses]]
+      type = "Apache-2.0"
+      uri = "https://www.apache.org/licenses/"
+
+  [[metadata.dependencies]]
+    id = "other-dep"
+    version = "2.0.0"
+    uri = "https://localhost/other-dep-2.0.0.tgz"
+    stacks = ["*"]
+`), 0600)).To(Succeed())
+		})
+
+		it("writes dependency keys in the canonical order", func() {
+			original, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(internal.UpdateTOMLFile(path, internal.TOMLOptions{}, func(md map[string]interface{}) error {
+				return nil
+			})).To(Succeed())
+
+			Expect(os.ReadFile(path)).To(Equal(original))
+		})
+
+		it("keeps the order when a value changes", func() {
+			Expect(internal.UpdateTOMLFile(path, internal.TOMLOptions{}, func(md map[string]interface{}) error {
+				deps := md["metadata"].(map[string]interface{})["dependencies"].([]map[string]interface{})
+				deps[0]["version"] = "1.0.1"
+				return nil
+			})).To(Succeed())
+
+			Expect(os.ReadFile(path)).To(ContainSubstring(`    id = "some-dep"
+    name = "Some Dependency"
+    version = "1.0.1"
+    uri = "https://localhost/some-dep-1.0.0.tgz"
+`))
+		})
+	})
+
+	context("TableArray", func() {
+		it("accepts an array of tables", func() {
+			tables, ok := internal.TableArray([]map[string]interface{}{{"id": "some-id"}})
+			Expect(ok).To(BeTrue())
+			Expect(tables).To(Equal([]map[string]interface{}{{"id": "some-id"}}))
+		})
+
+		it("accepts an inline array of tables", func() {
+			tables, ok := internal.TableArray([]interface{}{map[string]interface{}{"id": "some-id"}})
+			Expect(ok).To(BeTrue())
+			Expect(tables).To(Equal([]map[string]interface{}{{"id": "some-id"}}))
+		})
+
+		it("rejects an array with a value that is not a table", func() {
+			_, ok := internal.TableArray([]interface{}{map[string]interface{}{"id": "some-id"}, "some-value"})
+			Expect(ok).To(BeFalse())
+		})
+
+		it("rejects a value that is not an array", func() {
+			_, ok := internal.TableArray("some-value")
+			Expect(ok).To(BeFalse())
+		})
+	})
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package internal
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const uriCheckTimeout = 30 * time.Second
+
+// CheckURI confirms that uri is reachable without downloading it. It sends a HEAD request and, if that does not
+// succeed, because some servers do not support HEAD, a GET for the first byte. Redirects are followed and the final
+// status is returned, with an error if it is not 2xx.
+func CheckURI(uri string) (int, error) {
+	if Offline() {
+		return 0, fmt.Errorf("unable to reach %s\n%w", uri, ErrOffline)
+	}
+
+	client := http.Client{Timeout: uriCheckTimeout}
+
+	status, err := requestStatus(&client, http.MethodHead, uri)
+	if err == nil && isSuccess(status) {
+		return status, nil
+	}
+
+	status, err = requestStatus(&client, http.MethodGet, uri)
+	if err != nil {
+		return 0, fmt.Errorf("unable to reach %s\n%w", uri, err)
+	}
+
+	if !isSuccess(status) {
+		return status, fmt.Errorf("unable to reach %s, status: %d", uri, status)
+	}
+
+	return status, nil
+}
+
+func requestStatus(client *http.Client, method string, uri string) (int, error) {
+	req, err := http.NewRequest(method, uri, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	return res.StatusCode, nil
+}
+
+func isSuccess(status int) bool {
+	return status >= 200 && status <= 299
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package internal_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libpak-tools/internal"
+)
+
+func testCheckURI(t *testing.T, _ spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		methods []string
+		server  *httptest.Server
+	)
+
+	it.Before(func() {
+		methods = nil
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+			methods = append(methods, r.Method)
+		})
+		mux.HandleFunc("/no-head", func(w http.ResponseWriter, r *http.Request) {
+			methods = append(methods, r.Method)
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			Expect(r.Header.Get("Range")).To(Equal("bytes=0-0"))
+			w.WriteHeader(http.StatusPartialContent)
+		})
+		mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		})
+		mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+		})
+		server = httptest.NewServer(mux)
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	it("uses HEAD when supported", func() {
+		Expect(internal.CheckURI(server.URL + "/ok")).To(Equal(http.StatusOK))
+		Expect(methods).To(Equal([]string{http.MethodHead}))
+	})
+
+	it("falls back to a ranged GET", func() {
+		Expect(internal.CheckURI(server.URL + "/no-head")).To(Equal(http.StatusPartialContent))
+		Expect(methods).To(Equal([]string{http.MethodHead, http.MethodGet}))
+	})
+
+	it("follows redirects", func() {
+		Expect(internal.CheckURI(server.URL + "/redirect")).To(Equal(http.StatusOK))
+	})
+
+	it("fails when the uri is not reachable", func() {
+		status, err := internal.CheckURI(server.URL + "/missing")
+		Expect(status).To(Equal(http.StatusNotFound))
+		Expect(err).To(MatchError(ContainSubstring("status: 404")))
+	})
+}
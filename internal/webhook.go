@@ -0,0 +1,52 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const webhookTimeout = 30 * time.Second
+
+// PostWebhook sends payload, encoded as JSON, to url
+func PostWebhook(url string, payload interface{}) error {
+	if Offline() {
+		return fmt.Errorf("unable to post to webhook %s\n%w", url, ErrOffline)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("unable to encode webhook payload\n%w", err)
+	}
+
+	client := http.Client{Timeout: webhookTimeout}
+	res, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("unable to post to webhook %s\n%w", url, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unable to post to webhook %s, status: %d", url, res.StatusCode)
+	}
+
+	return nil
+}
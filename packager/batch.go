@@ -0,0 +1,154 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package packager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BatchEntry is a buildpack to package as part of a batch
+type BatchEntry struct {
+	// BuildpackID is the id of the buildpack
+	BuildpackID string
+
+	// BuildpackPath is the directory of the buildpack, it is inferred from BuildpackID and BP_ROOT when empty
+	BuildpackPath string
+
+	// BuildpackVersion is the version to package, it is inferred from git when empty
+	BuildpackVersion string
+}
+
+// BatchResult is the outcome of packaging one BatchEntry
+type BatchResult struct {
+	BuildpackID      string
+	BuildpackVersion string
+
+	// Digest is the digest, or local image ID, of the packaged image
+	Digest string
+
+	Err error
+}
+
+// ReadBuildpacksFile reads one `id` or `id@version` per line, ignoring blank lines and `#` comments
+func ReadBuildpacksFile(path string) ([]BatchEntry, error) {
+	c, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s\n%w", path, err)
+	}
+
+	var entries []BatchEntry
+	for _, line := range strings.Split(string(c), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		id, version, _ := strings.Cut(line, "@")
+		entries = append(entries, BatchEntry{BuildpackID: id, BuildpackVersion: version})
+	}
+
+	return entries, nil
+}
+
+// ExpandBuildpackPaths returns an entry for each directory matching the glob pattern that has a buildpack.toml, with
+// the id and version read from it. A templated version, like `{{.version}}`, is left empty so that it is inferred.
+func ExpandBuildpackPaths(pattern string) ([]BatchEntry, error) {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid buildpack path pattern %s\n%w", pattern, err)
+	}
+
+	var entries []BatchEntry
+	for _, match := range matches {
+		buildpackToml := filepath.Join(match, "buildpack.toml")
+		if _, err := os.Stat(buildpackToml); err != nil {
+			continue
+		}
+
+		var bp struct {
+			Buildpack struct {
+				ID      string `toml:"id"`
+				Version string `toml:"version"`
+			} `toml:"buildpack"`
+		}
+		if err := decodeTOMLFile(buildpackToml, &bp); err != nil {
+			return nil, err
+		}
+
+		if bp.Buildpack.ID == "" {
+			return nil, fmt.Errorf("%s has no [buildpack] id", buildpackToml)
+		}
+
+		version := bp.Buildpack.Version
+		if strings.Contains(version, "{{") {
+			version = ""
+		}
+
+		entries = append(entries, BatchEntry{BuildpackID: bp.Buildpack.ID, BuildpackPath: match, BuildpackVersion: version})
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%s matches no directories with a buildpack.toml", pattern)
+	}
+
+	return entries, nil
+}
+
+// ExecuteBatch packages each entry in turn using p as a template, continuing past failures. Each entry is packaged as
+// its id from its own directory, so the RegistryName and ConfigDir of p are not used.
+func (p BundleBuildpack) ExecuteBatch(entries []BatchEntry) []BatchResult {
+	var results []BatchResult
+
+	for _, entry := range entries {
+		bp := p
+		bp.BuildpackID = entry.BuildpackID
+		bp.BuildpackPath = entry.BuildpackPath
+		bp.BuildpackVersion = entry.BuildpackVersion
+		bp.RegistryName = entry.BuildpackID
+		bp.ConfigDir = ""
+
+		fmt.Fprintf(bp.progress(), "➜ Bundle Buildpack: %s\n", entry.BuildpackID)
+		err := bp.executeBatchEntry()
+		results = append(results, BatchResult{
+			BuildpackID:      bp.BuildpackID,
+			BuildpackVersion: bp.BuildpackVersion,
+			Digest:           bp.ResultDigest,
+			Err:              err,
+		})
+	}
+
+	return results
+}
+
+func (p *BundleBuildpack) executeBatchEntry() error {
+	if p.BuildpackPath == "" {
+		if err := p.InferBuildpackPath(); err != nil {
+			return err
+		}
+	}
+
+	if p.BuildpackVersion == "" {
+		if err := p.InferBuildpackVersion(); err != nil {
+			return err
+		}
+	}
+
+	return p.Execute()
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package packager_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/v2/effect"
+	"github.com/paketo-buildpacks/libpak/v2/effect/mocks"
+	"github.com/sclevine/spec"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/paketo-buildpacks/libpak-tools/packager"
+)
+
+func testBatch(t *testing.T, _ spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		root string
+	)
+
+	it.Before(func() {
+		root = t.TempDir()
+		t.Setenv("BP_ROOT", root)
+		t.Setenv("BP_ARCH", "amd64")
+	})
+
+	it("reads ids and versions from a buildpacks file", func() {
+		path := filepath.Join(t.TempDir(), "buildpacks.txt")
+		Expect(os.WriteFile(path, []byte(`# buildpacks to release
+paketo-buildpacks/one@1.2.3
+
+  paketo-buildpacks/two
+`), 0600)).To(Succeed())
+
+		entries, err := packager.ReadBuildpacksFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(Equal([]packager.BatchEntry{
+			{BuildpackID: "paketo-buildpacks/one", BuildpackVersion: "1.2.3"},
+			{BuildpackID: "paketo-buildpacks/two"},
+		}))
+	})
+
+	it("expands a buildpack path pattern", func() {
+		dir := t.TempDir()
+		Expect(os.MkdirAll(filepath.Join(dir, "one"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "one", "buildpack.toml"), []byte(`[buildpack]
+id = "some-org/one"
+version = "{{.version}}"
+`), 0600)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(dir, "two"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "two", "buildpack.toml"), []byte(`[buildpack]
+id = "some-org/two"
+version = "2.0.0"
+`), 0600)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(dir, "docs"), 0755)).To(Succeed())
+
+		entries, err := packager.ExpandBuildpackPaths(filepath.Join(dir, "*"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(Equal([]packager.BatchEntry{
+			{BuildpackID: "some-org/one", BuildpackPath: filepath.Join(dir, "one")},
+			{BuildpackID: "some-org/two", BuildpackPath: filepath.Join(dir, "two"), BuildpackVersion: "2.0.0"},
+		}))
+	})
+
+	it("fails when a buildpack path pattern matches no buildpacks", func() {
+		_, err := packager.ExpandBuildpackPaths(filepath.Join(t.TempDir(), "*"))
+		Expect(err).To(MatchError(ContainSubstring("matches no directories with a buildpack.toml")))
+	})
+
+	it("packages each buildpack and continues past failures", func() {
+		Expect(os.MkdirAll(filepath.Join(root, "paketo-buildpacks", "one"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, "paketo-buildpacks", "one", "package.toml"), []byte(""), 0600)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(root, "paketo-buildpacks", "two"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(root, "paketo-buildpacks", "three"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, "paketo-buildpacks", "three", "package.toml"), []byte(""), 0600)).To(Succeed())
+
+		mockExecutor := &mocks.Executor{}
+		mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+			return e.Command == "pack"
+		})).Return(nil)
+		mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+			return e.Command == "docker"
+		})).Return(nil)
+
+		p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+		results := p.ExecuteBatch([]packager.BatchEntry{
+			{BuildpackID: "paketo-buildpacks/one", BuildpackVersion: "1.0.0"},
+			{BuildpackID: "paketo-buildpacks/two", BuildpackVersion: "2.0.0"},
+			{BuildpackID: "paketo-buildpacks/three", BuildpackVersion: "3.0.0"},
+		})
+
+		Expect(results).To(HaveLen(3))
+		Expect(results[0].BuildpackID).To(Equal("paketo-buildpacks/one"))
+		Expect(results[0].Err).NotTo(HaveOccurred())
+		Expect(results[1].BuildpackID).To(Equal("paketo-buildpacks/two"))
+		Expect(results[1].Err).To(MatchError(ContainSubstring("unable to open package.toml")))
+		Expect(results[2].BuildpackID).To(Equal("paketo-buildpacks/three"))
+		Expect(results[2].Err).NotTo(HaveOccurred())
+
+		mockExecutor.AssertCalled(t, "Execute", mock.MatchedBy(func(e effect.Execution) bool {
+			return e.Command == "pack" && e.Args[2] == "paketo-buildpacks/three"
+		}))
+	})
+
+	it("packages each buildpack as its id from its own directory", func() {
+		Expect(os.MkdirAll(filepath.Join(root, "paketo-buildpacks", "one"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(root, "paketo-buildpacks", "one", "package.toml"), []byte(""), 0600)).To(Succeed())
+
+		mockExecutor := &mocks.Executor{}
+		mockExecutor.On("Execute", mock.Anything).Return(nil)
+
+		p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+		p.RegistryName = "some-registry/some-name"
+		p.ConfigDir = filepath.Join(root, "does-not-exist")
+		results := p.ExecuteBatch([]packager.BatchEntry{
+			{BuildpackID: "paketo-buildpacks/one", BuildpackVersion: "1.0.0"},
+		})
+
+		Expect(results).To(HaveLen(1))
+		Expect(results[0].Err).NotTo(HaveOccurred())
+		mockExecutor.AssertCalled(t, "Execute", mock.MatchedBy(func(e effect.Execution) bool {
+			return e.Command == "pack" && e.Args[2] == "paketo-buildpacks/one"
+		}))
+	})
+}
@@ -18,24 +18,43 @@ package packager
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/BurntSushi/toml"
 	"github.com/buildpacks/libcnb/v2"
 	"github.com/paketo-buildpacks/libpak/v2/effect"
+	"github.com/paketo-buildpacks/libpak/v2/log"
 	"github.com/paketo-buildpacks/libpak/v2/sherpa"
 
 	"github.com/paketo-buildpacks/libpak-tools/carton"
+	"github.com/paketo-buildpacks/libpak-tools/internal"
 )
 
+// BuildLabel is added to every image packaged by libpak-tools, so that cleaning up only removes those images
+const BuildLabel = "io.paketo.libpak-tools.build"
+
 type BundleBuildpack struct {
 	// BuildpackPath is the location to the buildpack source files
 	BuildpackPath string
 
+	// ConfigDir is where buildpack.toml and package.toml of a composite buildpack are read from, defaults to
+	// BuildpackPath. Packaging still runs in BuildpackPath.
+	ConfigDir string
+
 	// BuildpackID is the id of the buildpack you want to package
 	BuildpackID string
 
@@ -51,6 +70,9 @@ type BundleBuildpack struct {
 	// StrictDependencyFilters indicates that a filter must match both the ID and version, otherwise it must only match one of the two
 	StrictDependencyFilters bool
 
+	// ExcludeDependencyFilters are regular expressions that exclude a dependency when they match its ID or Version
+	ExcludeDependencyFilters []string
+
 	// IncludeDependencies indicates whether to include dependencies in build package.
 	IncludeDependencies bool
 
@@ -60,13 +82,87 @@ type BundleBuildpack struct {
 	// Publish indicates whether to publish the buildpack to the registry
 	Publish bool
 
+	// PackageTimeout bounds how long `pack buildpack package` may run, there is no limit when zero
+	PackageTimeout time.Duration
+
+	// PullPolicy is the pull policy passed to pack, `always`, `never` or `if-not-present`. It defaults to BP_PULL_POLICY
+	// or `if-not-present`.
+	PullPolicy string
+
+	// PackBinary is the pack command used to package the buildpack, defaults to BP_PACK_BINARY or `pack`
+	PackBinary string
+
+	// ContainerEngine is the container CLI used to clean up images, defaults to BP_CONTAINER_ENGINE or `docker`
+	ContainerEngine string
+
+	// AllowedRegistries restricts the registry hosts that may be published to, any host is allowed when empty
+	AllowedRegistries []string
+
+	// GitBinary is the git command used to infer the buildpack version, defaults to `git`
+	GitBinary string
+
+	// GitArgs are extra global arguments passed to git before the `describe` sub-command. Each value is split on
+	// whitespace, so `-c safe.directory=*` can be passed as one value.
+	GitArgs []string
+
+	// PlatformAPI is the platform API pack should target, as `<major>.<minor>`. It is passed to pack as
+	// CNB_PLATFORM_API, so it only has an effect with pack versions that honor that variable.
+	PlatformAPI string
+
+	// Progress is where progress, including the output of pack, is written, defaults to os.Stderr so that stdout is
+	// kept for command output
+	Progress io.Writer
+
+	// Flatten is `auto`, `true` or `false`. With `auto`, or when empty, composite buildpacks are flattened unless
+	// BP_FLATTEN_DISABLED is set and component buildpacks are not. `true` and `false` override BP_FLATTEN_DISABLED for
+	// both.
+	Flatten string
+
+	// OutputFile writes the buildpack to this .cnb file with `--format file` instead of creating an image. No image is
+	// inspected, signed or cleaned up. It cannot be combined with Publish.
+	OutputFile string
+
+	// OS is the operating system packaged for, `linux` or `windows`, defaults to BP_OS or `linux`
+	OS string
+
+	// StrictArch fails, instead of warning, when a local build targets another arch than the host and there is no qemu
+	// emulation registered for it
+	StrictArch bool
+
+	// SummaryFile is where Execute writes a JSON BundleSummary of what it did, nothing is written when empty
+	SummaryFile string
+
+	// Verbose writes the diagnostics of git and the container engine to stderr, instead of discarding them
+	Verbose bool
+
+	// Labels are `key=value` labels added to the packaged image, like org.opencontainers.image.source. They are not
+	// supported for extensions.
+	Labels []string
+
+	// BuildID is the value of the BuildLabel added to the images packaged by this run, it is generated when empty
+	BuildID string
+
+	// SkipClean skips removing dangling images after packaging, it is also set by BP_SKIP_IMAGE_CLEANUP
+	SkipClean bool
+
+	// Sign signs the published image with `cosign sign` once it has been pushed. A key is read from COSIGN_KEY, keyless
+	// signing is used otherwise. It has no effect unless Publish is set.
+	Sign bool
+
+	// CosignBinary is the cosign command used to sign the image, defaults to `cosign`
+	CosignBinary string
+
+	// ResultDigest is set by Execute to the digest of the published image, or to the image ID when the image is only
+	// saved to the local daemon
+	ResultDigest string
+
 	executor    effect.Executor
 	exitHandler libcnb.ExitHandler
 }
 
 func NewBundleBuildpack() BundleBuildpack {
 	return BundleBuildpack{
-		executor: effect.NewExecutor(),
+		executor: NewExecutor(),
 	}
 }
 
@@ -92,6 +188,16 @@ func (p *BundleBuildpack) InferBuildpackPath() error {
 	bpType := bpParts[0]
 	bpName := bpParts[1]
 
+	orgMap, err := readOrgMap()
+	if err != nil {
+		return err
+	}
+
+	if dir, found := orgMap[bpType]; found {
+		p.BuildpackPath = filepath.Join(root, dir, bpName)
+		return nil
+	}
+
 	switch bpType {
 	case "paketobuildpacks":
 		p.BuildpackPath = filepath.Join(root, "paketo-buildpacks", bpName)
@@ -104,22 +210,142 @@ func (p *BundleBuildpack) InferBuildpackPath() error {
 	return nil
 }
 
-// InferBuildpackVersion from git state or default to DEV
+// InferBuildpackID reads the buildpack id from the `[buildpack]` id of the buildpack.toml in the config directory, or
+// from the `[extension]` id of the extension.toml of an extension
+func (p *BundleBuildpack) InferBuildpackID() error {
+	kind := packageKind(p.configDir())
+	path := filepath.Join(p.configDir(), fmt.Sprintf("%s.toml", kind))
+
+	var md struct {
+		Buildpack struct {
+			ID string `toml:"id"`
+		} `toml:"buildpack"`
+		Extension struct {
+			ID string `toml:"id"`
+		} `toml:"extension"`
+	}
+	if err := decodeTOMLFile(path, &md); err != nil {
+		return err
+	}
+
+	id := md.Buildpack.ID
+	if kind == "extension" {
+		id = md.Extension.ID
+	}
+
+	if id == "" {
+		return fmt.Errorf("%s has no [%s] id", path, kind)
+	}
+
+	p.BuildpackID = id
+	return nil
+}
+
+// ListBuildpackIDs lists the ids of the buildpacks cloned under BP_ROOT, which are the directories in
+// `$BP_ROOT/paketo-buildpacks`, `$BP_ROOT/paketo-community` and the directories of the orgs in BP_ORG_MAP. There are no
+// ids when BP_ROOT is not set.
+func ListBuildpackIDs() ([]string, error) {
+	root, found := os.LookupEnv("BP_ROOT")
+	if !found || root == "" {
+		return nil, nil
+	}
+
+	orgMap, err := readOrgMap()
+	if err != nil {
+		return nil, err
+	}
+
+	orgs := map[string]string{
+		"paketo-buildpacks": "paketo-buildpacks",
+		"paketo-community":  "paketo-community",
+	}
+	for org, dir := range orgMap {
+		orgs[org] = dir
+	}
+
+	var ids []string
+	for org, dir := range orgs {
+		entries, err := os.ReadDir(filepath.Join(root, dir))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("unable to read %s\n%w", filepath.Join(root, dir), err)
+		}
+
+		for _, e := range entries {
+			if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+				ids = append(ids, fmt.Sprintf("%s/%s", org, e.Name()))
+			}
+		}
+	}
+
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// readOrgMap reads the mapping of buildpack id orgs to directory names from the file in BP_ORG_MAP, which is TOML if it
+// has a .toml extension and JSON otherwise. The mapping is empty if BP_ORG_MAP is not set.
+func readOrgMap() (map[string]string, error) {
+	path, found := os.LookupEnv("BP_ORG_MAP")
+	if !found || path == "" {
+		return nil, nil
+	}
+
+	c, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read BP_ORG_MAP %s\n%w", path, err)
+	}
+
+	orgMap := map[string]string{}
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		err = toml.Unmarshal(c, &orgMap)
+	} else {
+		err = json.Unmarshal(c, &orgMap)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode BP_ORG_MAP %s\n%w", path, err)
+	}
+
+	return orgMap, nil
+}
+
+// InferBuildpackVersion from git state, a VERSION file in the buildpack path or default to DEV
 func (p *BundleBuildpack) InferBuildpackVersion() error {
 	buf := bytes.Buffer{}
 
+	gitBinary := p.GitBinary
+	if gitBinary == "" {
+		gitBinary = "git"
+	}
+
+	var args []string
+	for _, arg := range p.GitArgs {
+		args = append(args, strings.Fields(arg)...)
+	}
+	args = append(args, "describe", "--tags", "--match", "v*")
+
 	err := p.executor.Execute(effect.Execution{
-		Command: "git",
-		Args:    []string{"describe", "--tags", "--match", "v*"},
+		Command: gitBinary,
+		Args:    args,
 		Stdout:  &buf,
-		Stderr:  io.Discard,
+		Stderr:  p.diagnostics(),
 		Dir:     p.BuildpackPath,
 	})
-	if err != nil {
-		return fmt.Errorf("unable to execute git command\n%w", err)
+	gitResult := strings.TrimSpace(buf.String())
+
+	// shallow checkouts and source tarballs have no tags, or no git repository, so fall back to a VERSION file
+	if err != nil || gitResult == "" {
+		version, versionErr := readVersionFile(p.BuildpackPath)
+		if versionErr != nil {
+			return versionErr
+		}
+
+		if version == "" && err != nil {
+			return fmt.Errorf("unable to execute git command\n%w", err)
+		}
+		gitResult = version
 	}
 
-	gitResult := strings.TrimSpace(buf.String())
 	if gitResult == "" {
 		gitResult = "DEV"
 	}
@@ -129,11 +355,31 @@ func (p *BundleBuildpack) InferBuildpackVersion() error {
 	return nil
 }
 
-// CleanUpDockerImages removes dangling docker images created by the build process
+// readVersionFile returns the trimmed contents of the VERSION file in path, or an empty string if there is none
+func readVersionFile(path string) (string, error) {
+	c, err := os.ReadFile(filepath.Join(path, "VERSION"))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("unable to read VERSION\n%w", err)
+	}
+
+	return strings.TrimSpace(string(c)), nil
+}
+
+// CleanUpDockerImages removes dangling docker images created by the build process. Only images with the BuildLabel
+// set to the BuildID of this run are removed, so images packaged by concurrent builds on a shared host are left alone.
 func (p *BundleBuildpack) CleanUpDockerImages() error {
+	engine := p.containerEngine()
+
+	buildID, err := p.buildID()
+	if err != nil {
+		return err
+	}
+
 	buf := &bytes.Buffer{}
-	err := p.executor.Execute(effect.Execution{
-		Command: "docker",
+	err = p.executor.Execute(effect.Execution{
+		Command: engine,
 		Args: []string{
 			"image",
 			"ls",
@@ -141,12 +387,14 @@ func (p *BundleBuildpack) CleanUpDockerImages() error {
 			"--no-trunc",
 			"--filter",
 			"dangling=true",
+			"--filter",
+			fmt.Sprintf("label=%s=%s", BuildLabel, buildID),
 		},
 		Stdout: buf,
-		Stderr: io.Discard,
+		Stderr: p.diagnostics(),
 	})
 	if err != nil {
-		return fmt.Errorf("unable to execute `docker image ls` command\n%w", err)
+		return fmt.Errorf("unable to execute `%s image ls` command\n%w", engine, err)
 	}
 
 	imagesToClean := []string{}
@@ -158,63 +406,394 @@ func (p *BundleBuildpack) CleanUpDockerImages() error {
 
 	if len(imagesToClean) > 0 {
 		err = p.executor.Execute(effect.Execution{
-			Command: "docker",
+			Command: engine,
 			Args: append([]string{
 				"image",
 				"rm",
 				"-f",
 			}, imagesToClean...),
 			Stdout: io.Discard,
-			Stderr: io.Discard,
+			Stderr: p.diagnostics(),
 		})
 		if err != nil {
-			return fmt.Errorf("unable to execute `docker image rm` command on images %v\n%w", imagesToClean, err)
+			return fmt.Errorf("unable to execute `%s image rm` command on images %v\n%w", engine, imagesToClean, err)
 		}
 	}
 
 	return nil
 }
 
+// InspectDigest returns the digest of the packaged image. A published image is looked up in the registry, with
+// `docker buildx imagetools inspect` or, as podman has no equivalent, with `skopeo inspect`. Otherwise the ID of the
+// image in the local engine is returned.
+func (p *BundleBuildpack) InspectDigest() (string, error) {
+	command := p.containerEngine()
+
+	args := []string{"image", "inspect", "--format", "{{.Id}}", p.imageName()}
+	if p.Publish && filepath.Base(command) == "podman" {
+		command = "skopeo"
+		args = []string{"inspect", "--format", "{{.Digest}}", fmt.Sprintf("docker://%s", p.imageName())}
+	} else if p.Publish {
+		args = []string{"buildx", "imagetools", "inspect", "--format", "{{.Manifest.Digest}}", p.imageName()}
+	}
+
+	buf := &bytes.Buffer{}
+	err := p.executor.Execute(effect.Execution{
+		Command: command,
+		Args:    args,
+		Stdout:  buf,
+		Stderr:  p.diagnostics(),
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to execute `%s %s` command\n%w", command, strings.Join(args, " "), err)
+	}
+
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// SignImage signs the published image by its ResultDigest with `cosign sign`. The key is read from COSIGN_KEY, when it
+// is not set cosign signs keyless using its own environment.
+func (p *BundleBuildpack) SignImage() error {
+	if p.ResultDigest == "" {
+		return fmt.Errorf("the digest of %s is unknown", p.imageName())
+	}
+
+	ref := carton.ParseImageReference(p.imageName())
+	ref.Tag = ""
+	ref.Digest = p.ResultDigest
+
+	args := []string{"sign", "--yes"}
+	if key, found := os.LookupEnv("COSIGN_KEY"); found && key != "" {
+		args = append(args, "--key", key)
+	}
+	args = append(args, ref.String())
+
+	cosign := p.CosignBinary
+	if cosign == "" {
+		cosign = "cosign"
+	}
+
+	p.logger().Debugf("Executing %s %s", cosign, strings.Join(args, " "))
+	err := p.executor.Execute(effect.Execution{
+		Command: cosign,
+		Args:    args,
+		Stdout:  p.progress(),
+		Stderr:  os.Stderr,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to execute `cosign sign` command\n%w", err)
+	}
+
+	return nil
+}
+
+// buildID returns BuildID, generating it if it is empty
+func (p *BundleBuildpack) buildID() (string, error) {
+	if p.BuildID == "" {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return "", fmt.Errorf("unable to generate build id\n%w", err)
+		}
+		p.BuildID = hex.EncodeToString(b)
+	}
+
+	return p.BuildID, nil
+}
+
+// configDir returns the directory buildpack.toml and package.toml are read from
+func (p *BundleBuildpack) configDir() string {
+	if p.ConfigDir != "" {
+		return p.ConfigDir
+	}
+
+	return p.BuildpackPath
+}
+
+// imageName returns the name the buildpack image is packaged as
+func (p *BundleBuildpack) imageName() string {
+	if p.RegistryName != "" {
+		return p.RegistryName
+	}
+
+	return p.BuildpackID
+}
+
+// containerEngine returns the configured container CLI, `docker` or `podman`
+func (p *BundleBuildpack) containerEngine() string {
+	if p.ContainerEngine != "" {
+		return p.ContainerEngine
+	}
+
+	return sherpa.GetEnvWithDefault("BP_CONTAINER_ENGINE", "docker")
+}
+
 // ExecutePackage runs the package buildpack command
 func (p *BundleBuildpack) ExecutePackage(workingDirectory string, additionalArgs ...string) error {
-	pullPolicy, found := os.LookupEnv("BP_PULL_POLICY")
-	if !found {
-		pullPolicy = "if-not-present"
+	pullPolicy := p.pullPolicy()
+	if err := ValidatePullPolicy(pullPolicy); err != nil {
+		return err
 	}
 
-	imageName := p.BuildpackID
-	if p.RegistryName != "" {
-		imageName = p.RegistryName
+	imageName := p.imageName()
+	if p.OutputFile != "" {
+		imageName = p.OutputFile
 	}
 
+	kind := packageKind(workingDirectory)
+
 	args := []string{
-		"buildpack",
+		kind,
 		"package",
 		imageName,
 		"--pull-policy", pullPolicy,
 	}
 
+	if p.OutputFile != "" {
+		if p.Publish {
+			return fmt.Errorf("output file and publish cannot both be set")
+		}
+
+		args = append(args, "--format", "file")
+	}
+
 	if p.Publish {
+		if err := p.checkRegistryAllowed(imageName); err != nil {
+			return err
+		}
+
 		args = append(args, "--publish")
 	} else {
-		args = append(args, "--target", archFromSystem())
+		targetOS := p.targetOS()
+		if err := ValidateTarget(targetOS, targetArch()); err != nil {
+			return err
+		}
+
+		if targetOS == "linux" {
+			if err := CheckEmulation(targetArch(), binfmtMiscDir); err != nil {
+				if p.StrictArch {
+					return err
+				}
+				fmt.Fprintf(p.progress(), "Warning: %s\n", err)
+			}
+		}
+
+		args = append(args, "--target", fmt.Sprintf("%s/%s", targetOS, targetArch()))
 	}
 
-	args = append(args, additionalArgs...)
-	err := p.executor.Execute(effect.Execution{
-		Command: "pack",
+	buildID, err := p.buildID()
+	if err != nil {
+		return err
+	}
+
+	for _, label := range p.Labels {
+		if key, value, found := strings.Cut(label, "="); !found || key == "" || value == "" {
+			return fmt.Errorf("invalid label %q, must be key=value", label)
+		}
+	}
+
+	// pack extension package does not support --flatten or --label, so extension images are not labeled for clean up
+	if kind == "buildpack" {
+		args = append(args, additionalArgs...)
+		for _, label := range p.Labels {
+			args = append(args, "--label", label)
+		}
+		args = append(args, "--label", fmt.Sprintf("%s=%s", BuildLabel, buildID))
+	} else if len(p.Labels) > 0 {
+		return fmt.Errorf("labels are not supported when packaging an extension")
+	}
+
+	if err := ValidateFlatten(p.Flatten); err != nil {
+		return err
+	}
+
+	var env []string
+	if p.PlatformAPI != "" {
+		if err := ValidatePlatformAPI(p.PlatformAPI); err != nil {
+			return err
+		}
+
+		env = append(os.Environ(), fmt.Sprintf("CNB_PLATFORM_API=%s", p.PlatformAPI))
+	}
+
+	ctx := context.Background()
+	if p.PackageTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.PackageTimeout)
+		defer cancel()
+	}
+
+	p.logger().Debugf("Executing %s %s", p.packBinary(), strings.Join(args, " "))
+	err = executeContext(ctx, p.executor, effect.Execution{
+		Command: p.packBinary(),
 		Args:    args,
-		Stdout:  os.Stdout,
+		Env:     env,
+		Stdout:  p.progress(),
 		Stderr:  os.Stderr,
 		Dir:     workingDirectory,
 	})
-	if err != nil {
-		return fmt.Errorf("unable to execute `pack buildpack package` command\n%w", err)
+	var exitErr *exec.ExitError
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("`pack %s package` did not finish within %s", kind, p.PackageTimeout)
+	} else if errors.As(err, &exitErr) {
+		return fmt.Errorf("unable to execute `pack %s package` command\n%w", kind, PackExitError{Code: exitErr.ExitCode(), Err: err})
+	} else if err != nil {
+		return fmt.Errorf("unable to execute `pack %s package` command\n%w", kind, err)
+	}
+
+	return nil
+}
+
+// PackExitError is returned, wrapped, by ExecutePackage when pack ran but exited with a non-zero code, which is a
+// packaging failure rather than pack being missing or failing to start
+type PackExitError struct {
+	// Code is the exit code of pack
+	Code int
+
+	Err error
+}
+
+func (e PackExitError) Error() string {
+	return fmt.Sprintf("pack exited with code %d\n%s", e.Code, e.Err)
+}
+
+func (e PackExitError) Unwrap() error {
+	return e.Err
+}
+
+// packageKind returns `extension` if dir has an extension.toml and no buildpack.toml, so that it is packaged with
+// `pack extension package`, and `buildpack` otherwise
+func packageKind(dir string) string {
+	if _, err := os.Stat(filepath.Join(dir, "buildpack.toml")); err == nil {
+		return "buildpack"
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "extension.toml")); err == nil {
+		return "extension"
+	}
+
+	return "buildpack"
+}
+
+var platformAPIPattern = regexp.MustCompile(`^[0-9]+\.[0-9]+$`)
+
+// ValidatePlatformAPI fails if api is not a `<major>.<minor>` platform API version
+func ValidatePlatformAPI(api string) error {
+	if !platformAPIPattern.MatchString(api) {
+		return fmt.Errorf("invalid platform api %q, must be <major>.<minor> like 0.12", api)
 	}
 
 	return nil
 }
 
+// progress returns the configured progress writer
+func (p *BundleBuildpack) progress() io.Writer {
+	if p.Progress != nil {
+		return p.Progress
+	}
+
+	return os.Stderr
+}
+
+// diagnostics returns where the stderr of git and the container engine is written, which is discarded unless Verbose
+// is set
+func (p *BundleBuildpack) diagnostics() io.Writer {
+	if p.Verbose {
+		return os.Stderr
+	}
+
+	return io.Discard
+}
+
+// logger returns a logger writing to the progress writer, debug messages are only written when BP_LOG_LEVEL is debug
+func (p *BundleBuildpack) logger() log.Logger {
+	return log.NewPaketoLogger(p.progress())
+}
+
+// ValidateFlatten fails if flatten is not `auto`, `true` or `false`
+func ValidateFlatten(flatten string) error {
+	switch flatten {
+	case "", "auto", "true", "false":
+		return nil
+	default:
+		return fmt.Errorf("invalid flatten %q, must be auto, true or false", flatten)
+	}
+}
+
+// ValidatePullPolicy fails if policy is not `always`, `never` or `if-not-present`
+func ValidatePullPolicy(policy string) error {
+	switch policy {
+	case "always", "never", "if-not-present":
+		return nil
+	default:
+		return fmt.Errorf("invalid pull policy %q, must be always, never or if-not-present", policy)
+	}
+}
+
+// pullPolicy returns PullPolicy, or BP_PULL_POLICY, or `if-not-present` if neither is set. It is always `never` when
+// BP_OFFLINE is set, so that pack does not pull images.
+func (p *BundleBuildpack) pullPolicy() string {
+	if internal.Offline() {
+		return "never"
+	}
+
+	if p.PullPolicy != "" {
+		return p.PullPolicy
+	}
+
+	return sherpa.GetEnvWithDefault("BP_PULL_POLICY", "if-not-present")
+}
+
+// flattenArgs returns the pack arguments that flatten the buildpack, if it should be
+func (p *BundleBuildpack) flattenArgs(composite bool) []string {
+	switch p.Flatten {
+	case "true":
+		return []string{"--flatten"}
+	case "false":
+		return nil
+	default:
+		if composite && !sherpa.ResolveBool("BP_FLATTEN_DISABLED") {
+			return []string{"--flatten"}
+		}
+		return nil
+	}
+}
+
+// packBinary returns the configured pack command
+func (p *BundleBuildpack) packBinary() string {
+	if p.PackBinary != "" {
+		return p.PackBinary
+	}
+
+	return sherpa.GetEnvWithDefault("BP_PACK_BINARY", "pack")
+}
+
+// checkRegistryAllowed fails if AllowedRegistries is set and does not contain the registry host of the image
+func (p *BundleBuildpack) checkRegistryAllowed(imageName string) error {
+	if len(p.AllowedRegistries) == 0 {
+		return nil
+	}
+
+	host := registryHost(imageName)
+	for _, allowed := range p.AllowedRegistries {
+		if strings.EqualFold(host, allowed) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("publishing to registry %s is not allowed, allowed registries are: %s", host, strings.Join(p.AllowedRegistries, ", "))
+}
+
+// registryHost returns the registry host of an image name, following the docker convention that the first path
+// component is only a host if it contains a `.` or `:` or is `localhost`
+func registryHost(imageName string) string {
+	first, _, found := strings.Cut(imageName, "/")
+	if found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		return first
+	}
+
+	return "docker.io"
+}
+
 // CompilePackage compiles the buildpack's Go code
 func (p *BundleBuildpack) CompilePackage(destDir string) {
 	pkg := carton.Package{}
@@ -223,11 +802,13 @@ func (p *BundleBuildpack) CompilePackage(destDir string) {
 	pkg.CacheLocation = p.CacheLocation
 	pkg.DependencyFilters = p.DependencyFilters
 	pkg.StrictDependencyFilters = p.StrictDependencyFilters
+	pkg.ExcludeDependencyFilters = p.ExcludeDependencyFilters
 	pkg.IncludeDependencies = p.IncludeDependencies
 	pkg.Destination = destDir
 
 	options := []carton.Option{
 		carton.WithExecutor(p.executor),
+		carton.WithProgressWriter(p.progress()),
 	}
 	if p.exitHandler != nil {
 		options = append(options, carton.WithExitHandler(p.exitHandler))
@@ -237,17 +818,34 @@ func (p *BundleBuildpack) CompilePackage(destDir string) {
 
 func (p *BundleBuildpack) CompileAndBundleComponent(buildDirectory string) error {
 	// Compile the buildpack
-	fmt.Println("➜ Compile Buildpack")
+	fmt.Fprintln(p.progress(), "➜ Compile Buildpack")
 	p.CompilePackage(buildDirectory)
 
 	// package the buildpack
-	fmt.Printf("➜ Package Buildpack: %s\n", p.BuildpackID)
-	return p.ExecutePackage(buildDirectory)
+	fmt.Fprintf(p.progress(), "➜ Package Buildpack: %s\n", p.BuildpackID)
+	return p.ExecutePackage(buildDirectory, p.flattenArgs(false)...)
+}
+
+// Compile compiles a component buildpack into destination without packaging it, so that the buildpack directory can be
+// inspected. Composite buildpacks have nothing to compile and fail.
+func (p *BundleBuildpack) Compile(destination string) error {
+	if componentBp, err := p.IsComponent(); err != nil {
+		return err
+	} else if !componentBp {
+		return fmt.Errorf("%s is a composite buildpack, only component buildpacks can be compiled", p.BuildpackPath)
+	} else if p.ConfigDir != "" {
+		return errConfigDirComponent(p.BuildpackPath)
+	}
+
+	fmt.Fprintln(p.progress(), "➜ Compile Buildpack")
+	p.CompilePackage(destination)
+
+	return nil
 }
 
 func (p *BundleBuildpack) BundleComposite(buildDirectory string) error {
 	// Make a modified package.toml in the temp directory
-	packageTomlPath, err := copyPackageTomlAndAddURI(p.BuildpackPath, buildDirectory)
+	packageTomlPath, err := copyPackageTomlAndAddURI(p.configDir(), buildDirectory)
 	if err != nil {
 		return fmt.Errorf("unable to copy package.toml and add URI\n%w", err)
 	}
@@ -257,17 +855,16 @@ func (p *BundleBuildpack) BundleComposite(buildDirectory string) error {
 		"--config", packageTomlPath,
 	}
 
-	if !sherpa.ResolveBool("BP_FLATTEN_DISABLED") {
-		args = append(args, "--flatten")
-	}
+	args = append(args, p.flattenArgs(true)...)
 
-	// we still package from the buildpack directory though, only the package.toml is in the temp directory
-	fmt.Printf("➜ Package Buildpack: %s\n", p.BuildpackID)
+	// we still package from the buildpack directory though, only the package.toml is in the temp directory and its
+	// uri points to the directory with buildpack.toml
+	fmt.Fprintf(p.progress(), "➜ Package Buildpack: %s\n", p.BuildpackID)
 	return p.ExecutePackage(p.BuildpackPath, args...)
 }
 
-func copyPackageTomlAndAddURI(buildpackPath, destDir string) (string, error) {
-	inputPackageToml, err := os.Open(filepath.Join(buildpackPath, "package.toml"))
+func copyPackageTomlAndAddURI(configDir, destDir string) (string, error) {
+	inputPackageToml, err := os.Open(filepath.Join(configDir, "package.toml"))
 	if err != nil {
 		return "", fmt.Errorf("unable to open package.toml\n%w", err)
 	}
@@ -280,7 +877,7 @@ func copyPackageTomlAndAddURI(buildpackPath, destDir string) (string, error) {
 	}
 	defer outputPackageToml.Close()
 
-	_, err = outputPackageToml.WriteString(fmt.Sprintf("[buildpack]\nuri = \"%s\"\n\n", buildpackPath))
+	_, err = outputPackageToml.WriteString(fmt.Sprintf("[buildpack]\nuri = \"%s\"\n\n", configDir))
 	if err != nil {
 		return "", fmt.Errorf("unable to write uri\n%w", err)
 	}
@@ -293,25 +890,153 @@ func copyPackageTomlAndAddURI(buildpackPath, destDir string) (string, error) {
 	return outputPackageTomlPath, nil
 }
 
+// IsComponent returns true if the buildpack is a component buildpack, which has no [[order]] in its buildpack.toml.
+// When there is no buildpack.toml, a buildpack is a component if it has a Go cmd/main/main.go. Extensions, with an
+// extension.toml instead, are always components.
+func (p *BundleBuildpack) IsComponent() (bool, error) {
+	if packageKind(p.configDir()) == "extension" {
+		return true, nil
+	}
+
+	buildpackToml := filepath.Join(p.configDir(), "buildpack.toml")
+	if found, err := sherpa.FileExists(buildpackToml); err != nil {
+		return false, fmt.Errorf("unable to check if file exists\n%w", err)
+	} else if found {
+		var bp struct {
+			Order []interface{} `toml:"order"`
+		}
+		if err := decodeTOMLFile(buildpackToml, &bp); err != nil {
+			return false, err
+		}
+
+		return len(bp.Order) == 0, nil
+	}
+
+	componentBp, err := sherpa.FileExists(filepath.Join(p.BuildpackPath, "cmd/main/main.go"))
+	if err != nil {
+		return false, fmt.Errorf("unable to check if file exists\n%w", err)
+	}
+
+	return componentBp, nil
+}
+
+// errConfigDirComponent is returned when ConfigDir is set for a component buildpack, which is always compiled from
+// BuildpackPath
+func errConfigDirComponent(path string) error {
+	return fmt.Errorf("%s is a component buildpack, config-dir is only supported for composite buildpacks", path)
+}
+
 // Execute runs the package buildpack command
 func (p *BundleBuildpack) Execute() error {
+	summary := p.newSummary()
+	return p.writeSummary(summary, p.execute(&summary))
+}
+
+func (p *BundleBuildpack) execute(summary *BundleSummary) error {
 	buildDirectory, err := os.MkdirTemp("", "BundleBuildpack")
 	if err != nil {
 		return fmt.Errorf("unable to create temporary directory\n%w", err)
 	}
 
-	// we use existence of main.go to determine if we are packaging a component or composite buildpack
-	mainCmdPath := filepath.Join(p.BuildpackPath, "cmd/main/main.go")
-	if componentBp, err := sherpa.FileExists(mainCmdPath); err != nil {
-		return fmt.Errorf("unable to check if file exists\n%w", err)
+	if componentBp, err := p.IsComponent(); err != nil {
+		return err
 	} else if componentBp {
-		p.CompileAndBundleComponent(buildDirectory)
+		summary.Kind = "component"
+		if p.ConfigDir != "" {
+			return errConfigDirComponent(p.BuildpackPath)
+		}
+		if err := p.CompileAndBundleComponent(buildDirectory); err != nil {
+			return err
+		}
 	} else {
-		p.BundleComposite(buildDirectory)
+		summary.Kind = "composite"
+		if err := p.BundleComposite(buildDirectory); err != nil {
+			return err
+		}
+	}
+
+	return p.finish(summary)
+}
+
+// BundleDirectory packages a buildpack that has already been built into dir, which must contain a buildpack.toml and
+// a bin directory. Nothing is compiled, BuildpackID is read from the buildpack.toml if it is empty.
+func (p *BundleBuildpack) BundleDirectory(dir string) error {
+	var bp struct {
+		Buildpack struct {
+			ID string `toml:"id"`
+		} `toml:"buildpack"`
+	}
+	if err := decodeTOMLFile(filepath.Join(dir, "buildpack.toml"), &bp); err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(filepath.Join(dir, "bin")); err != nil || !info.IsDir() {
+		return fmt.Errorf("%s does not contain a bin directory", dir)
+	}
+
+	if p.BuildpackID == "" {
+		p.BuildpackID = bp.Buildpack.ID
+	}
+
+	fmt.Fprintf(p.progress(), "➜ Package Buildpack: %s\n", p.BuildpackID)
+	return p.ExecutePackage(dir, p.flattenArgs(false)...)
+}
+
+// ExecuteDirectory packages a pre-built buildpack directory without compiling it, then inspects, signs and cleans up
+// like Execute
+func (p *BundleBuildpack) ExecuteDirectory(dir string) error {
+	summary := p.newSummary()
+	summary.Kind = "component"
+
+	err := p.BundleDirectory(dir)
+
+	// the buildpack id may have been read from buildpack.toml
+	summary.BuildpackID = p.BuildpackID
+	if p.OutputFile == "" {
+		summary.Image = p.imageName()
+	}
+
+	if err != nil {
+		return p.writeSummary(summary, err)
+	}
+
+	return p.writeSummary(summary, p.finish(&summary))
+}
+
+// finish records the digest of the packaged image, signs it if requested and cleans up dangling images, unless the
+// buildpack was written to OutputFile
+func (p *BundleBuildpack) finish(summary *BundleSummary) error {
+	summary.Packaged = true
+
+	// there is no image when the buildpack is written to a file
+	if p.OutputFile != "" {
+		return nil
+	}
+
+	// the digest is only required to sign the image or to record it in the summary, otherwise it is informational
+	var err error
+	p.ResultDigest, err = p.InspectDigest()
+	if err != nil && ((p.Sign && p.Publish) || p.SummaryFile != "") {
+		return fmt.Errorf("unable to inspect digest of %s\n%w", p.imageName(), err)
+	} else if err != nil {
+		fmt.Fprintf(p.progress(), "Warning: unable to inspect digest of %s\n%s\n", p.imageName(), err)
+	}
+	summary.Digest = p.ResultDigest
+
+	if p.Sign && p.Publish {
+		fmt.Fprintln(p.progress(), "➜ Signing Buildpack")
+		if err := p.SignImage(); err != nil {
+			return fmt.Errorf("unable to sign %s\n%w", p.imageName(), err)
+		}
+		summary.Signed = true
+	}
+
+	// clean up, unless other jobs on a shared host may be using the dangling images
+	if p.SkipClean || sherpa.ResolveBool("BP_SKIP_IMAGE_CLEANUP") {
+		return nil
 	}
 
-	// clean up
-	fmt.Println("➜ Cleaning up Docker images")
+	fmt.Fprintln(p.progress(), "➜ Cleaning up Docker images")
 	err = p.CleanUpDockerImages()
 	if err != nil {
 		return fmt.Errorf("unable to clean up docker images\n%w", err)
@@ -320,11 +1045,72 @@ func (p *BundleBuildpack) Execute() error {
 	return nil
 }
 
-func archFromSystem() string {
+// targetOS returns OS, or BP_OS, or `linux` if neither is set
+func (p *BundleBuildpack) targetOS() string {
+	if p.OS != "" {
+		return p.OS
+	}
+
+	return sherpa.GetEnvWithDefault("BP_OS", "linux")
+}
+
+// supportedTargets are the arches pack can package a buildpack for on each operating system
+var supportedTargets = map[string][]string{
+	"linux":   {"amd64", "arm64", "arm", "386", "ppc64le", "s390x"},
+	"windows": {"amd64"},
+}
+
+// ValidateTarget fails if pack cannot package a buildpack for the os and arch
+func ValidateTarget(os string, arch string) error {
+	arches, found := supportedTargets[os]
+	if !found {
+		return fmt.Errorf("invalid os %q, must be linux or windows", os)
+	}
+
+	if !slices.Contains(arches, arch) {
+		return fmt.Errorf("unsupported target %s/%s, %s supports %s", os, arch, os, strings.Join(arches, ", "))
+	}
+
+	return nil
+}
+
+// targetArch returns BP_ARCH, or the arch of the host if it is not set
+func targetArch() string {
 	archFromEnv, ok := os.LookupEnv("BP_ARCH")
 	if !ok {
 		archFromEnv = runtime.GOARCH
 	}
 
-	return "linux/" + archFromEnv
+	return archFromEnv
+}
+
+const binfmtMiscDir = "/proc/sys/fs/binfmt_misc"
+
+// qemuArches maps Go arches to the names qemu registers its binfmt_misc handlers under
+var qemuArches = map[string]string{
+	"amd64":   "x86_64",
+	"arm64":   "aarch64",
+	"ppc64le": "ppc64le",
+	"s390x":   "s390x",
+}
+
+// CheckEmulation fails if arch is not the arch of the host and there is no enabled qemu handler for it in binfmtDir, so
+// that building for it locally would fail
+func CheckEmulation(arch string, binfmtDir string) error {
+	if arch == runtime.GOARCH {
+		return nil
+	}
+
+	qemuArch, found := qemuArches[arch]
+	if !found {
+		qemuArch = arch
+	}
+
+	c, err := os.ReadFile(filepath.Join(binfmtDir, fmt.Sprintf("qemu-%s", qemuArch)))
+	if err == nil && bytes.HasPrefix(c, []byte("enabled")) {
+		return nil
+	}
+
+	return fmt.Errorf("building for %s on a %s host requires qemu emulation, but no qemu-%s handler is enabled in %s, "+
+		"install it with e.g. docker run --privileged --rm tonistiigi/binfmt --install %s", arch, runtime.GOARCH, qemuArch, binfmtDir, arch)
 }
@@ -16,10 +16,17 @@
 package packager_test
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"testing"
+	"time"
 
 	exMocks "github.com/buildpacks/libcnb/v2/mocks"
 	. "github.com/onsi/gomega"
@@ -83,6 +90,129 @@ func testBuildpack(t *testing.T, context spec.G, it spec.S) {
 				Expect(p.InferBuildpackPath()).To(Succeed())
 				Expect(p.BuildpackPath).To(Equal("/some/path/paketo-buildpacks/foo"))
 			})
+
+			context("BP_ORG_MAP is set", func() {
+				var dir string
+
+				it.Before(func() {
+					dir = t.TempDir()
+				})
+
+				it("maps the org with a JSON file", func() {
+					Expect(os.WriteFile(filepath.Join(dir, "orgs.json"), []byte(`{ "acme": "acme-buildpacks" }`), 0600)).To(Succeed())
+					t.Setenv("BP_ORG_MAP", filepath.Join(dir, "orgs.json"))
+
+					p.BuildpackID = "acme/foo"
+					Expect(p.InferBuildpackPath()).To(Succeed())
+					Expect(p.BuildpackPath).To(Equal("/some/path/acme-buildpacks/foo"))
+				})
+
+				it("maps the org with a TOML file", func() {
+					Expect(os.WriteFile(filepath.Join(dir, "orgs.toml"), []byte(`acme = "acme-buildpacks"`), 0600)).To(Succeed())
+					t.Setenv("BP_ORG_MAP", filepath.Join(dir, "orgs.toml"))
+
+					p.BuildpackID = "acme/foo"
+					Expect(p.InferBuildpackPath()).To(Succeed())
+					Expect(p.BuildpackPath).To(Equal("/some/path/acme-buildpacks/foo"))
+				})
+
+				it("is consulted before the built-in mappings", func() {
+					Expect(os.WriteFile(filepath.Join(dir, "orgs.json"), []byte(`{ "paketobuildpacks": "mirror" }`), 0600)).To(Succeed())
+					t.Setenv("BP_ORG_MAP", filepath.Join(dir, "orgs.json"))
+
+					p.BuildpackID = "paketobuildpacks/foo"
+					Expect(p.InferBuildpackPath()).To(Succeed())
+					Expect(p.BuildpackPath).To(Equal("/some/path/mirror/foo"))
+				})
+
+				it("falls back to the built-in mappings", func() {
+					Expect(os.WriteFile(filepath.Join(dir, "orgs.json"), []byte(`{ "acme": "acme-buildpacks" }`), 0600)).To(Succeed())
+					t.Setenv("BP_ORG_MAP", filepath.Join(dir, "orgs.json"))
+
+					p.BuildpackID = "paketocommunity/foo"
+					Expect(p.InferBuildpackPath()).To(Succeed())
+					Expect(p.BuildpackPath).To(Equal("/some/path/paketo-community/foo"))
+				})
+
+				it("fails if the file cannot be decoded", func() {
+					Expect(os.WriteFile(filepath.Join(dir, "orgs.json"), []byte(`not json`), 0600)).To(Succeed())
+					t.Setenv("BP_ORG_MAP", filepath.Join(dir, "orgs.json"))
+
+					p.BuildpackID = "acme/foo"
+					Expect(p.InferBuildpackPath()).To(MatchError(HavePrefix(fmt.Sprintf("unable to decode BP_ORG_MAP %s", filepath.Join(dir, "orgs.json")))))
+				})
+			})
+		})
+	})
+
+	context("Infer Buildpack ID", func() {
+		var path string
+
+		it.Before(func() {
+			path = t.TempDir()
+		})
+
+		it("reads the id from buildpack.toml", func() {
+			Expect(os.WriteFile(filepath.Join(path, "buildpack.toml"), []byte(`api = "0.10"
+
+[buildpack]
+id = "some-org/some-buildpack"
+`), 0600)).To(Succeed())
+
+			p := packager.NewBundleBuildpackForTests(nil, nil)
+			p.BuildpackPath = path
+
+			Expect(p.InferBuildpackID()).To(Succeed())
+			Expect(p.BuildpackID).To(Equal("some-org/some-buildpack"))
+		})
+
+		it("reads the id from extension.toml", func() {
+			Expect(os.WriteFile(filepath.Join(path, "extension.toml"), []byte(`api = "0.10"
+
+[extension]
+id = "some-org/some-extension"
+`), 0600)).To(Succeed())
+
+			p := packager.NewBundleBuildpackForTests(nil, nil)
+			p.BuildpackPath = path
+
+			Expect(p.InferBuildpackID()).To(Succeed())
+			Expect(p.BuildpackID).To(Equal("some-org/some-extension"))
+		})
+
+		it("fails when buildpack.toml has no id", func() {
+			Expect(os.WriteFile(filepath.Join(path, "buildpack.toml"), []byte(`[buildpack]
+name = "Some Buildpack"
+`), 0600)).To(Succeed())
+
+			p := packager.NewBundleBuildpackForTests(nil, nil)
+			p.BuildpackPath = path
+
+			Expect(p.InferBuildpackID()).To(MatchError(ContainSubstring("has no [buildpack] id")))
+		})
+	})
+
+	context("List buildpack ids", func() {
+		it("lists no ids when BP_ROOT is not set", func() {
+			Expect(packager.ListBuildpackIDs()).To(BeEmpty())
+		})
+
+		it("lists the buildpack directories under BP_ROOT", func() {
+			root := t.TempDir()
+			Expect(os.MkdirAll(filepath.Join(root, "paketo-buildpacks", "bellsoft-liberica"), 0755)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(root, "paketo-buildpacks", ".github"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(root, "paketo-buildpacks", "README.md"), []byte{}, 0644)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(root, "paketo-community", "rust"), 0755)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(root, "acme-buildpacks", "widget"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(root, "orgs.json"), []byte(`{ "acme": "acme-buildpacks" }`), 0644)).To(Succeed())
+			t.Setenv("BP_ROOT", root)
+			t.Setenv("BP_ORG_MAP", filepath.Join(root, "orgs.json"))
+
+			Expect(packager.ListBuildpackIDs()).To(Equal([]string{
+				"acme/widget",
+				"paketo-buildpacks/bellsoft-liberica",
+				"paketo-community/rust",
+			}))
 		})
 	})
 
@@ -153,6 +283,132 @@ func testBuildpack(t *testing.T, context spec.G, it spec.S) {
 			Expect(p.BuildpackVersion).To(Equal("DEV"))
 		})
 
+		context("VERSION file", func() {
+			var path string
+
+			it.Before(func() {
+				path = t.TempDir()
+				Expect(os.WriteFile(filepath.Join(path, "VERSION"), []byte("4.5.6\n"), 0600)).To(Succeed())
+			})
+
+			it("uses the VERSION file when git returns nothing", func() {
+				mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+					return e.Command == "git"
+				})).Return(nil)
+
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.BuildpackPath = path
+
+				Expect(p.InferBuildpackVersion()).To(Succeed())
+				Expect(p.BuildpackVersion).To(Equal("4.5.6"))
+			})
+
+			it("uses the VERSION file when git fails", func() {
+				mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+					return e.Command == "git"
+				})).Return(fmt.Errorf("fatal: No names found, cannot describe anything."))
+
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.BuildpackPath = path
+
+				Expect(p.InferBuildpackVersion()).To(Succeed())
+				Expect(p.BuildpackVersion).To(Equal("4.5.6"))
+			})
+
+			it("prefers the git tag over the VERSION file", func() {
+				mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+					return e.Command == "git"
+				})).Return(func(ex effect.Execution) error {
+					_, err := ex.Stdout.Write([]byte("v1.2.3"))
+					Expect(err).ToNot(HaveOccurred())
+					return nil
+				})
+
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.BuildpackPath = path
+
+				Expect(p.InferBuildpackVersion()).To(Succeed())
+				Expect(p.BuildpackVersion).To(Equal("1.2.3"))
+			})
+		})
+
+		it("runs a custom git binary with extra global args", func() {
+			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				Expect(e.Command).To(Equal("/opt/git/bin/git"))
+				Expect(e.Args).To(HaveExactElements([]string{
+					"-c",
+					"safe.directory=*",
+					"describe",
+					"--tags",
+					"--match",
+					"v*",
+				}))
+				return true
+			})).Return(func(ex effect.Execution) error {
+				_, err := ex.Stdout.Write([]byte("v1.2.3"))
+				Expect(err).ToNot(HaveOccurred())
+				return nil
+			})
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackPath = "/some/path"
+			p.GitBinary = "/opt/git/bin/git"
+			p.GitArgs = []string{"-c", "safe.directory=*"}
+
+			Expect(p.InferBuildpackVersion()).To(Succeed())
+			Expect(p.BuildpackVersion).To(Equal("1.2.3"))
+		})
+
+		it("splits extra global args on whitespace", func() {
+			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				Expect(e.Args).To(HaveExactElements([]string{
+					"-c",
+					"safe.directory=*",
+					"-c",
+					"core.fsmonitor=false",
+					"describe",
+					"--tags",
+					"--match",
+					"v*",
+				}))
+				return true
+			})).Return(func(ex effect.Execution) error {
+				_, err := ex.Stdout.Write([]byte("v1.2.3"))
+				Expect(err).ToNot(HaveOccurred())
+				return nil
+			})
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackPath = "/some/path"
+			p.GitArgs = []string{"-c safe.directory=*", " -c  core.fsmonitor=false "}
+
+			Expect(p.InferBuildpackVersion()).To(Succeed())
+			Expect(p.BuildpackVersion).To(Equal("1.2.3"))
+		})
+
+		it("discards the stderr of git", func() {
+			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "git" && e.Stderr == io.Discard
+			})).Return(nil)
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackPath = "/some/path"
+
+			Expect(p.InferBuildpackVersion()).To(Succeed())
+		})
+
+		it("writes the stderr of git to stderr when verbose", func() {
+			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "git" && e.Stderr == os.Stderr
+			})).Return(nil)
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackPath = "/some/path"
+			p.Verbose = true
+
+			Expect(p.InferBuildpackVersion()).To(Succeed())
+		})
+
 		it("runs git tags which fails", func() {
 			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
 				return e.Command == "git" &&
@@ -201,7 +457,9 @@ func testBuildpack(t *testing.T, context spec.G, it spec.S) {
 					e.Args[2] == "--quiet" &&
 					e.Args[3] == "--no-trunc" &&
 					e.Args[4] == "--filter" &&
-					e.Args[5] == "dangling=true"
+					e.Args[5] == "dangling=true" &&
+					e.Args[6] == "--filter" &&
+					e.Args[7] == "label=io.paketo.libpak-tools.build=some-build-id"
 			})).Return(func(ex effect.Execution) error {
 				_, err := ex.Stdout.Write([]byte("  "))
 				Expect(err).ToNot(HaveOccurred())
@@ -210,6 +468,7 @@ func testBuildpack(t *testing.T, context spec.G, it spec.S) {
 
 			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
 			p.BuildpackPath = "/some/path"
+			p.BuildID = "some-build-id"
 
 			Expect(p.CleanUpDockerImages()).To(Succeed())
 		})
@@ -246,149 +505,773 @@ func testBuildpack(t *testing.T, context spec.G, it spec.S) {
 		})
 	})
 
-	context("Run pack buildpack package", func() {
+	context("Clean up images with podman", func() {
 		var mockExecutor *mocks.Executor
 
 		it.Before(func() {
 			mockExecutor = &mocks.Executor{}
+			t.Setenv("BP_CONTAINER_ENGINE", "podman")
 		})
 
-		it("defaults pull policy to if-not-present", func() {
+		it("uses the container engine from BP_CONTAINER_ENGINE", func() {
 			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
-				return e.Command == "pack" &&
-					e.Args[0] == "buildpack" &&
-					e.Args[1] == "package" &&
-					e.Args[2] == "some-id" &&
-					e.Args[3] == "--pull-policy" &&
-					e.Args[4] == "if-not-present" &&
-					e.Args[5] == "--target" &&
-					e.Args[6] == "linux/amd64" &&
-					e.Dir == "/some/path"
+				return e.Command == "podman" &&
+					e.Args[0] == "image" &&
+					e.Args[1] == "ls"
+			})).Return(func(ex effect.Execution) error {
+				_, err := ex.Stdout.Write([]byte("foo\n"))
+				Expect(err).ToNot(HaveOccurred())
+				return nil
+			})
+
+			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "podman" &&
+					e.Args[0] == "image" &&
+					e.Args[1] == "rm" &&
+					e.Args[2] == "-f" &&
+					e.Args[3] == "foo"
 			})).Return(nil)
 
 			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
-			p.BuildpackID = "some-id"
 
-			Expect(p.ExecutePackage("/some/path")).To(Succeed())
+			Expect(p.CleanUpDockerImages()).To(Succeed())
+			mockExecutor.AssertNumberOfCalls(t, "Execute", 2)
 		})
 
-		it("include registry prefix if set", func() {
+		it("prefers the container engine field over BP_CONTAINER_ENGINE", func() {
 			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
-				return e.Command == "pack" &&
-					e.Args[0] == "buildpack" &&
-					e.Args[1] == "package" &&
-					e.Args[2] == "docker.io/some-other-id/image" &&
-					e.Args[3] == "--pull-policy" &&
-					e.Args[4] == "if-not-present" &&
-					e.Args[5] == "--publish" &&
-					e.Dir == "/some/path"
-			})).Return(nil)
+				return e.Command == "/usr/local/bin/docker"
+			})).Return(fmt.Errorf("engine fails"))
 
 			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
-			p.BuildpackID = "some-id"
-			p.RegistryName = "docker.io/some-other-id/image"
-			p.Publish = true
+			p.ContainerEngine = "/usr/local/bin/docker"
 
-			Expect(p.ExecutePackage("/some/path")).To(Succeed())
+			Expect(p.CleanUpDockerImages()).To(MatchError(ContainSubstring("unable to execute `/usr/local/bin/docker image ls` command")))
 		})
+	})
 
-		context("BP_PULL_POLICY is set", func() {
-			it.Before(func() {
-				t.Setenv("BP_PULL_POLICY", "always")
-			})
-
-			it("uses a specific pull policy", func() {
-				mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
-					return e.Command == "pack" &&
-						e.Args[0] == "buildpack" &&
-						e.Args[1] == "package" &&
-						e.Args[2] == "some-id" &&
-						e.Args[3] == "--pull-policy" &&
-						e.Args[4] == "always" &&
-						e.Args[5] == "--target" &&
-						e.Args[6] == "linux/amd64" &&
-						e.Dir == "/some/path"
-				})).Return(nil)
+	context("Inspect digest", func() {
+		var mockExecutor *mocks.Executor
 
-				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
-				p.BuildpackID = "some-id"
+		it.Before(func() {
+			mockExecutor = &mocks.Executor{}
+		})
 
-				Expect(p.ExecutePackage("/some/path")).To(Succeed())
+		it("returns the local image id", func() {
+			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "docker" &&
+					e.Args[0] == "image" &&
+					e.Args[1] == "inspect" &&
+					e.Args[2] == "--format" &&
+					e.Args[3] == "{{.Id}}" &&
+					e.Args[4] == "some-registry/some-id"
+			})).Return(func(ex effect.Execution) error {
+				_, err := ex.Stdout.Write([]byte("sha256:1234567890abcdef\n"))
+				Expect(err).ToNot(HaveOccurred())
+				return nil
 			})
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+			p.RegistryName = "some-registry/some-id"
+
+			Expect(p.InspectDigest()).To(Equal("sha256:1234567890abcdef"))
 		})
 
-		it("includes additional args", func() {
+		it("returns the digest of a published image from the registry", func() {
 			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
-				return e.Command == "pack" &&
-					e.Args[0] == "buildpack" &&
-					e.Args[1] == "package" &&
-					e.Args[2] == "some-id" &&
-					e.Args[3] == "--pull-policy" &&
-					e.Args[4] == "if-not-present" &&
-					e.Args[5] == "--target" &&
-					e.Args[6] == "linux/amd64" &&
-					e.Args[7] == "--some-more-args" &&
-					e.Dir == "/some/path"
-			})).Return(nil)
+				return e.Command == "docker" &&
+					e.Args[0] == "buildx" &&
+					e.Args[1] == "imagetools" &&
+					e.Args[2] == "inspect" &&
+					e.Args[3] == "--format" &&
+					e.Args[4] == "{{.Manifest.Digest}}" &&
+					e.Args[5] == "some-id"
+			})).Return(func(ex effect.Execution) error {
+				_, err := ex.Stdout.Write([]byte("sha256:fedcba0987654321\n"))
+				Expect(err).ToNot(HaveOccurred())
+				return nil
+			})
 
 			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
 			p.BuildpackID = "some-id"
+			p.Publish = true
 
-			Expect(p.ExecutePackage("/some/path", "--some-more-args")).To(Succeed())
+			Expect(p.InspectDigest()).To(Equal("sha256:fedcba0987654321"))
 		})
-	})
 
-	context("CompilePackage", func() {
-		var (
-			mockExecutor    *mocks.Executor
-			mockExitHandler exMocks.ExitHandler
-		)
+		it("fails if the image cannot be inspected", func() {
+			mockExecutor.On("Execute", mock.Anything).Return(fmt.Errorf("docker fails"))
 
-		it.Before(func() {
-			mockExecutor = &mocks.Executor{}
-			mockExitHandler = exMocks.ExitHandler{}
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
 
-			t.Setenv("BP_ARCH", "amd64")
+			_, err := p.InspectDigest()
+			Expect(err).To(MatchError(ContainSubstring("unable to execute `docker image inspect --format {{.Id}} some-id` command")))
 		})
 
-		it("compiles the package", func() {
+		it("returns the digest of an image published with podman from the registry", func() {
 			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
-				return e.Command == "pack" &&
-					e.Args[0] == "buildpack" &&
-					e.Args[1] == "package" &&
-					e.Args[2] == "some-id" &&
-					e.Args[3] == "--pull-policy" &&
-					e.Args[4] == "if-not-present" &&
-					e.Args[5] == "--target" &&
-					e.Args[6] == "linux/amd64" &&
-					e.Dir == "/some/path"
-			})).Return(nil)
+				return e.Command == "skopeo" &&
+					e.Args[0] == "inspect" &&
+					e.Args[1] == "--format" &&
+					e.Args[2] == "{{.Digest}}" &&
+					e.Args[3] == "docker://some-id"
+			})).Return(func(ex effect.Execution) error {
+				_, err := ex.Stdout.Write([]byte("sha256:fedcba0987654321\n"))
+				Expect(err).ToNot(HaveOccurred())
+				return nil
+			})
 
-			p := packager.NewBundleBuildpackForTests(mockExecutor, &mockExitHandler)
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
 			p.BuildpackID = "some-id"
+			p.ContainerEngine = "podman"
+			p.Publish = true
 
-			Expect(p.ExecutePackage("/some/path")).To(Succeed())
+			Expect(p.InspectDigest()).To(Equal("sha256:fedcba0987654321"))
 		})
 	})
 
-	context("Bundles a Composite", func() {
-		var (
-			buildpackPath string
-			buildPath     string
-			mockExecutor  *mocks.Executor
-		)
+	context("Sign image", func() {
+		var mockExecutor *mocks.Executor
 
 		it.Before(func() {
-			buildpackPath = t.TempDir()
-			buildPath = t.TempDir()
 			mockExecutor = &mocks.Executor{}
+		})
 
-			Expect(os.WriteFile(filepath.Join(buildpackPath, "package.toml"), []byte("some-toml"), 0600)).To(Succeed())
+		it("signs the image by digest with the key from COSIGN_KEY", func() {
+			t.Setenv("COSIGN_KEY", "env://SOME_KEY")
+			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "cosign"
+			})).Return(nil)
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+			p.RegistryName = "registry.example.com:5000/some-org/some-id:1.2.3"
+			p.ResultDigest = "sha256:1234567890abcdef"
+
+			Expect(p.SignImage()).To(Succeed())
+			mockExecutor.AssertCalled(t, "Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return slices.Equal(e.Args, []string{"sign", "--yes", "--key", "env://SOME_KEY", "registry.example.com:5000/some-org/some-id@sha256:1234567890abcdef"})
+			}))
 		})
 
-		it("inserts the URI to package.toml and runs pack buildpack package", func() {
+		it("signs keyless when COSIGN_KEY is not set", func() {
+			t.Setenv("COSIGN_KEY", "")
 			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
-				Expect(e.Command).To(Equal("pack"))
+				return e.Command == "/usr/local/bin/cosign"
+			})).Return(nil)
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-org/some-id"
+			p.CosignBinary = "/usr/local/bin/cosign"
+			p.ResultDigest = "sha256:1234567890abcdef"
+
+			Expect(p.SignImage()).To(Succeed())
+			mockExecutor.AssertCalled(t, "Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return slices.Equal(e.Args, []string{"sign", "--yes", "some-org/some-id@sha256:1234567890abcdef"})
+			}))
+		})
+
+		it("fails if the digest is unknown", func() {
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+
+			Expect(p.SignImage()).To(MatchError("the digest of some-id is unknown"))
+			mockExecutor.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+
+		it("fails if cosign fails", func() {
+			mockExecutor.On("Execute", mock.Anything).Return(fmt.Errorf("cosign fails"))
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+			p.ResultDigest = "sha256:1234567890abcdef"
+
+			Expect(p.SignImage()).To(MatchError(ContainSubstring("cosign fails")))
+		})
+	})
+
+	context("Check emulation", func() {
+		var (
+			dir       string
+			otherArch string
+			qemuArch  string
+		)
+
+		it.Before(func() {
+			dir = t.TempDir()
+
+			otherArch, qemuArch = "arm64", "aarch64"
+			if runtime.GOARCH == "arm64" {
+				otherArch, qemuArch = "amd64", "x86_64"
+			}
+		})
+
+		it("passes for the host arch", func() {
+			Expect(packager.CheckEmulation(runtime.GOARCH, dir)).To(Succeed())
+		})
+
+		it("passes when a qemu handler is enabled", func() {
+			Expect(os.WriteFile(filepath.Join(dir, "qemu-"+qemuArch), []byte("enabled\ninterpreter /usr/bin/qemu\n"), 0600)).To(Succeed())
+
+			Expect(packager.CheckEmulation(otherArch, dir)).To(Succeed())
+		})
+
+		it("fails when the qemu handler is disabled", func() {
+			Expect(os.WriteFile(filepath.Join(dir, "qemu-"+qemuArch), []byte("disabled\n"), 0600)).To(Succeed())
+
+			Expect(packager.CheckEmulation(otherArch, dir)).To(MatchError(ContainSubstring("requires qemu emulation")))
+		})
+
+		it("fails when there is no qemu handler", func() {
+			Expect(packager.CheckEmulation(otherArch, dir)).To(MatchError(ContainSubstring(fmt.Sprintf("no qemu-%s handler is enabled in %s", qemuArch, dir))))
+		})
+	})
+
+	context("Run pack buildpack package", func() {
+		var mockExecutor *mocks.Executor
+
+		it.Before(func() {
+			mockExecutor = &mocks.Executor{}
+		})
+
+		it("defaults pull policy to if-not-present", func() {
+			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "pack" &&
+					e.Args[0] == "buildpack" &&
+					e.Args[1] == "package" &&
+					e.Args[2] == "some-id" &&
+					e.Args[3] == "--pull-policy" &&
+					e.Args[4] == "if-not-present" &&
+					e.Args[5] == "--target" &&
+					e.Args[6] == "linux/amd64" &&
+					e.Dir == "/some/path"
+			})).Return(nil)
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+
+			Expect(p.ExecutePackage("/some/path")).To(Succeed())
+		})
+
+		it("runs pack extension package for an extension", func() {
+			path := t.TempDir()
+			Expect(os.WriteFile(filepath.Join(path, "extension.toml"), []byte(`api = "0.10"
+[extension]
+id = "some-org/some-extension"
+`), 0600)).To(Succeed())
+
+			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "pack" && slices.Equal(e.Args, []string{
+					"extension", "package", "some-org/some-extension",
+					"--pull-policy", "if-not-present",
+					"--target", "linux/amd64",
+				})
+			})).Return(nil)
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-org/some-extension"
+			p.Flatten = "true"
+
+			Expect(p.ExecutePackage(path, "--flatten")).To(Succeed())
+		})
+
+		it("does not check emulation when publishing", func() {
+			t.Setenv("BP_ARCH", "some-arch")
+			mockExecutor.On("Execute", mock.Anything).Return(nil)
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+			p.Publish = true
+			p.StrictArch = true
+
+			Expect(p.ExecutePackage("/some/path")).To(Succeed())
+		})
+
+		it("fails for a foreign arch without emulation when strict", func() {
+			t.Setenv("BP_ARCH", "s390x")
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+			p.StrictArch = true
+
+			Expect(p.ExecutePackage("/some/path")).To(MatchError(ContainSubstring("building for s390x")))
+			mockExecutor.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+
+		it("warns for a foreign arch without emulation", func() {
+			t.Setenv("BP_ARCH", "s390x")
+			mockExecutor.On("Execute", mock.Anything).Return(nil)
+
+			progress := &bytes.Buffer{}
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+			p.Progress = progress
+
+			Expect(p.ExecutePackage("/some/path")).To(Succeed())
+			Expect(progress.String()).To(HavePrefix("Warning: building for s390x"))
+		})
+
+		context("os is set", func() {
+			it("targets windows/amd64 when BP_OS is windows", func() {
+				t.Setenv("BP_OS", "windows")
+				mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+					return e.Command == "pack" && slices.Equal(e.Args[5:7], []string{"--target", "windows/amd64"})
+				})).Return(nil)
+
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.BuildpackID = "some-id"
+
+				Expect(p.ExecutePackage("/some/path")).To(Succeed())
+			})
+
+			it("prefers os over BP_OS", func() {
+				t.Setenv("BP_OS", "windows")
+				mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+					return e.Command == "pack" && slices.Equal(e.Args[5:7], []string{"--target", "linux/amd64"})
+				})).Return(nil)
+
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.BuildpackID = "some-id"
+				p.OS = "linux"
+
+				Expect(p.ExecutePackage("/some/path")).To(Succeed())
+			})
+
+			it("rejects a target pack does not support", func() {
+				t.Setenv("BP_ARCH", "arm64")
+
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.BuildpackID = "some-id"
+				p.OS = "windows"
+
+				Expect(p.ExecutePackage("/some/path")).To(MatchError("unsupported target windows/arm64, windows supports amd64"))
+				mockExecutor.AssertNotCalled(t, "Execute", mock.Anything)
+			})
+
+			it("rejects an unknown os", func() {
+				Expect(packager.ValidateTarget("darwin", "arm64")).To(MatchError(`invalid os "darwin", must be linux or windows`))
+			})
+		})
+
+		it("include registry prefix if set", func() {
+			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "pack" &&
+					e.Args[0] == "buildpack" &&
+					e.Args[1] == "package" &&
+					e.Args[2] == "docker.io/some-other-id/image" &&
+					e.Args[3] == "--pull-policy" &&
+					e.Args[4] == "if-not-present" &&
+					e.Args[5] == "--publish" &&
+					e.Dir == "/some/path"
+			})).Return(nil)
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+			p.RegistryName = "docker.io/some-other-id/image"
+			p.Publish = true
+
+			Expect(p.ExecutePackage("/some/path")).To(Succeed())
+		})
+
+		it("fails if pack does not finish within the timeout", func() {
+			mockExecutor.On("Execute", mock.Anything).Return(func(ex effect.Execution) error {
+				time.Sleep(time.Second)
+				return nil
+			})
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+			p.PackageTimeout = 10 * time.Millisecond
+
+			Expect(p.ExecutePackage("/some/path")).To(MatchError("`pack buildpack package` did not finish within 10ms"))
+		})
+
+		context("BP_PACK_BINARY is set", func() {
+			it.Before(func() {
+				t.Setenv("BP_PACK_BINARY", "/opt/pack/pack-v0.35.0")
+			})
+
+			it("uses the custom pack binary", func() {
+				mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+					return e.Command == "/opt/pack/pack-v0.35.0" &&
+						e.Args[0] == "buildpack" &&
+						e.Args[1] == "package"
+				})).Return(nil)
+
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.BuildpackID = "some-id"
+
+				Expect(p.ExecutePackage("/some/path")).To(Succeed())
+			})
+
+			it("prefers the pack binary field", func() {
+				mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+					return e.Command == "pack-custom"
+				})).Return(nil)
+
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.BuildpackID = "some-id"
+				p.PackBinary = "pack-custom"
+
+				Expect(p.ExecutePackage("/some/path")).To(Succeed())
+			})
+		})
+
+		context("allowed registries are set", func() {
+			it("publishes to an allowed registry", func() {
+				mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+					return e.Command == "pack" &&
+						e.Args[2] == "gcr.io/some-org/image" &&
+						e.Args[5] == "--publish"
+				})).Return(nil)
+
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.RegistryName = "gcr.io/some-org/image"
+				p.AllowedRegistries = []string{"docker.io", "gcr.io"}
+				p.Publish = true
+
+				Expect(p.ExecutePackage("/some/path")).To(Succeed())
+			})
+
+			it("publishes to docker.io when the image has no registry host", func() {
+				mockExecutor.On("Execute", mock.Anything).Return(nil)
+
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.RegistryName = "some-org/image"
+				p.AllowedRegistries = []string{"docker.io"}
+				p.Publish = true
+
+				Expect(p.ExecutePackage("/some/path")).To(Succeed())
+			})
+
+			it("refuses to publish to a registry that is not allowed", func() {
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.RegistryName = "registry.example.com:5000/some-org/image"
+				p.AllowedRegistries = []string{"docker.io", "gcr.io"}
+				p.Publish = true
+
+				Expect(p.ExecutePackage("/some/path")).To(MatchError(
+					"publishing to registry registry.example.com:5000 is not allowed, allowed registries are: docker.io, gcr.io"))
+				mockExecutor.AssertNotCalled(t, "Execute", mock.Anything)
+			})
+
+			it("does not check the registry when not publishing", func() {
+				mockExecutor.On("Execute", mock.Anything).Return(nil)
+
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.RegistryName = "registry.example.com/some-org/image"
+				p.AllowedRegistries = []string{"gcr.io"}
+
+				Expect(p.ExecutePackage("/some/path")).To(Succeed())
+			})
+		})
+
+		context("BP_PULL_POLICY is set", func() {
+			it.Before(func() {
+				t.Setenv("BP_PULL_POLICY", "always")
+			})
+
+			it("uses a specific pull policy", func() {
+				mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+					return e.Command == "pack" &&
+						e.Args[0] == "buildpack" &&
+						e.Args[1] == "package" &&
+						e.Args[2] == "some-id" &&
+						e.Args[3] == "--pull-policy" &&
+						e.Args[4] == "always" &&
+						e.Args[5] == "--target" &&
+						e.Args[6] == "linux/amd64" &&
+						e.Dir == "/some/path"
+				})).Return(nil)
+
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.BuildpackID = "some-id"
+
+				Expect(p.ExecutePackage("/some/path")).To(Succeed())
+			})
+
+			it("prefers the pull policy that is set", func() {
+				mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+					return e.Command == "pack" && e.Args[3] == "--pull-policy" && e.Args[4] == "never"
+				})).Return(nil)
+
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.BuildpackID = "some-id"
+				p.PullPolicy = "never"
+
+				Expect(p.ExecutePackage("/some/path")).To(Succeed())
+			})
+		})
+
+		it("never pulls when offline", func() {
+			t.Setenv("BP_OFFLINE", "true")
+			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "pack" && e.Args[3] == "--pull-policy" && e.Args[4] == "never"
+			})).Return(nil)
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+			p.PullPolicy = "always"
+
+			Expect(p.ExecutePackage("/some/path")).To(Succeed())
+		})
+
+		it("returns a PackExitError when pack exits non-zero", func() {
+			exitErr := exec.Command("sh", "-c", "exit 3").Run()
+			mockExecutor.On("Execute", mock.Anything).Return(exitErr)
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+
+			err := p.ExecutePackage("/some/path")
+			var packErr packager.PackExitError
+			Expect(errors.As(err, &packErr)).To(BeTrue())
+			Expect(packErr.Code).To(Equal(3))
+		})
+
+		it("does not return a PackExitError when pack cannot be run", func() {
+			mockExecutor.On("Execute", mock.Anything).Return(exec.ErrNotFound)
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+
+			err := p.ExecutePackage("/some/path")
+			Expect(err).To(MatchError(exec.ErrNotFound))
+			Expect(errors.As(err, &packager.PackExitError{})).To(BeFalse())
+		})
+
+		it("fails for an invalid pull policy", func() {
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+			p.PullPolicy = "sometimes"
+
+			Expect(p.ExecutePackage("/some/path")).To(MatchError(`invalid pull policy "sometimes", must be always, never or if-not-present`))
+			mockExecutor.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+
+		context("platform api is set", func() {
+			it("passes the platform api to pack", func() {
+				mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+					return e.Command == "pack" &&
+						e.Args[2] == "some-id" &&
+						slices.Contains(e.Env, "CNB_PLATFORM_API=0.12")
+				})).Return(nil)
+
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.BuildpackID = "some-id"
+				p.PlatformAPI = "0.12"
+
+				Expect(p.ExecutePackage("/some/path")).To(Succeed())
+			})
+
+			it("rejects an invalid platform api", func() {
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.BuildpackID = "some-id"
+				p.PlatformAPI = "v0.12"
+
+				Expect(p.ExecutePackage("/some/path")).To(MatchError(`invalid platform api "v0.12", must be <major>.<minor> like 0.12`))
+				mockExecutor.AssertNotCalled(t, "Execute", mock.Anything)
+			})
+		})
+
+		it("writes the output of pack to the progress writer", func() {
+			progress := &bytes.Buffer{}
+			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "pack" && e.Stdout == progress && e.Stderr == os.Stderr
+			})).Return(nil)
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+			p.Progress = progress
+
+			Expect(p.ExecutePackage("/some/path")).To(Succeed())
+		})
+
+		it("logs the pack command when BP_LOG_LEVEL is debug", func() {
+			t.Setenv("BP_LOG_LEVEL", "debug")
+			mockExecutor.On("Execute", mock.Anything).Return(nil)
+
+			progress := &bytes.Buffer{}
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+			p.Progress = progress
+
+			Expect(p.ExecutePackage("/some/path")).To(Succeed())
+			Expect(progress.String()).To(ContainSubstring("Executing pack buildpack package some-id --pull-policy if-not-present"))
+		})
+
+		it("writes the output of pack to stderr by default", func() {
+			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "pack" && e.Stdout == os.Stderr
+			})).Return(nil)
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+
+			Expect(p.ExecutePackage("/some/path")).To(Succeed())
+		})
+
+		it("labels the image with a generated build id", func() {
+			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "pack"
+			})).Return(nil)
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+
+			Expect(p.ExecutePackage("/some/path")).To(Succeed())
+			Expect(p.BuildID).To(MatchRegexp(`^[0-9a-f]{16}$`))
+			mockExecutor.AssertCalled(t, "Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return slices.Equal(e.Args[len(e.Args)-2:], []string{"--label", "io.paketo.libpak-tools.build=" + p.BuildID})
+			}))
+		})
+
+		it("passes labels to pack before the build label", func() {
+			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "pack" && slices.Equal(e.Args[len(e.Args)-6:len(e.Args)-2], []string{
+					"--label", "org.opencontainers.image.source=https://github.com/some-org/some-buildpack",
+					"--label", "org.opencontainers.image.revision=abc123",
+				})
+			})).Return(nil)
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+			p.Labels = []string{
+				"org.opencontainers.image.source=https://github.com/some-org/some-buildpack",
+				"org.opencontainers.image.revision=abc123",
+			}
+
+			Expect(p.ExecutePackage("/some/path")).To(Succeed())
+		})
+
+		it("fails for a label that is not key=value", func() {
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+			p.Labels = []string{"some-label"}
+
+			Expect(p.ExecutePackage("/some/path")).To(MatchError(`invalid label "some-label", must be key=value`))
+			mockExecutor.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+
+		context("output file is set", func() {
+			it("writes the buildpack to the file", func() {
+				mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+					return e.Command == "pack" &&
+						e.Args[2] == "/some/out/some-id.cnb" &&
+						slices.Equal(e.Args[5:7], []string{"--format", "file"})
+				})).Return(nil)
+
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.BuildpackID = "some-id"
+				p.OutputFile = "/some/out/some-id.cnb"
+
+				Expect(p.ExecutePackage("/some/path")).To(Succeed())
+			})
+
+			it("rejects publishing", func() {
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.BuildpackID = "some-id"
+				p.OutputFile = "/some/out/some-id.cnb"
+				p.Publish = true
+
+				Expect(p.ExecutePackage("/some/path")).To(MatchError("output file and publish cannot both be set"))
+				mockExecutor.AssertNotCalled(t, "Execute", mock.Anything)
+			})
+		})
+
+		it("includes additional args", func() {
+			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "pack" &&
+					e.Args[0] == "buildpack" &&
+					e.Args[1] == "package" &&
+					e.Args[2] == "some-id" &&
+					e.Args[3] == "--pull-policy" &&
+					e.Args[4] == "if-not-present" &&
+					e.Args[5] == "--target" &&
+					e.Args[6] == "linux/amd64" &&
+					e.Args[7] == "--some-more-args" &&
+					e.Dir == "/some/path"
+			})).Return(nil)
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+
+			Expect(p.ExecutePackage("/some/path", "--some-more-args")).To(Succeed())
+		})
+	})
+
+	context("CompilePackage", func() {
+		var (
+			mockExecutor    *mocks.Executor
+			mockExitHandler exMocks.ExitHandler
+		)
+
+		it.Before(func() {
+			mockExecutor = &mocks.Executor{}
+			mockExitHandler = exMocks.ExitHandler{}
+
+			t.Setenv("BP_ARCH", "amd64")
+		})
+
+		it("compiles the package", func() {
+			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "pack" &&
+					e.Args[0] == "buildpack" &&
+					e.Args[1] == "package" &&
+					e.Args[2] == "some-id" &&
+					e.Args[3] == "--pull-policy" &&
+					e.Args[4] == "if-not-present" &&
+					e.Args[5] == "--target" &&
+					e.Args[6] == "linux/amd64" &&
+					e.Dir == "/some/path"
+			})).Return(nil)
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, &mockExitHandler)
+			p.BuildpackID = "some-id"
+
+			Expect(p.ExecutePackage("/some/path")).To(Succeed())
+		})
+	})
+
+	context("Compile", func() {
+		it("fails for a composite buildpack", func() {
+			path := t.TempDir()
+			Expect(os.WriteFile(filepath.Join(path, "buildpack.toml"), []byte(`api = "0.10"
+[buildpack]
+id = "some-org/some-composite"
+
+[[order]]
+[[order.group]]
+id = "some-org/some-component"
+`), 0600)).To(Succeed())
+
+			p := packager.NewBundleBuildpackForTests(nil, nil)
+			p.BuildpackPath = path
+
+			Expect(p.Compile(t.TempDir())).To(MatchError(ContainSubstring("only component buildpacks can be compiled")))
+		})
+	})
+
+	context("Bundles a Composite", func() {
+		var (
+			buildpackPath string
+			buildPath     string
+			mockExecutor  *mocks.Executor
+		)
+
+		it.Before(func() {
+			buildpackPath = t.TempDir()
+			buildPath = t.TempDir()
+			mockExecutor = &mocks.Executor{}
+
+			Expect(os.WriteFile(filepath.Join(buildpackPath, "package.toml"), []byte("some-toml"), 0600)).To(Succeed())
+		})
+
+		it("inserts the URI to package.toml and runs pack buildpack package", func() {
+			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				Expect(e.Command).To(Equal("pack"))
 				Expect(e.Args).To(HaveExactElements([]string{
 					"buildpack",
 					"package",
@@ -400,6 +1283,8 @@ func testBuildpack(t *testing.T, context spec.G, it spec.S) {
 					"--config",
 					filepath.Join(buildPath, "/package.toml"),
 					"--flatten",
+					"--label",
+					"io.paketo.libpak-tools.build=some-build-id",
 				}))
 				Expect(e.Dir).To(Equal(buildpackPath))
 				return true
@@ -408,6 +1293,7 @@ func testBuildpack(t *testing.T, context spec.G, it spec.S) {
 			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
 			p.BuildpackID = "some-id"
 			p.BuildpackPath = buildpackPath
+			p.BuildID = "some-build-id"
 
 			Expect(p.BundleComposite(buildPath)).To(Succeed())
 
@@ -417,5 +1303,411 @@ func testBuildpack(t *testing.T, context spec.G, it spec.S) {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(string(contents)).To(HavePrefix(fmt.Sprintf("[buildpack]\nuri = \"%s\"\n\n", buildpackPath)))
 		})
+
+		it("reads package.toml from the config dir", func() {
+			configDir := t.TempDir()
+			Expect(os.WriteFile(filepath.Join(configDir, "package.toml"), []byte("other-toml"), 0600)).To(Succeed())
+
+			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "pack" && e.Dir == buildpackPath
+			})).Return(nil)
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+			p.BuildpackPath = buildpackPath
+			p.ConfigDir = configDir
+
+			Expect(p.BundleComposite(buildPath)).To(Succeed())
+
+			contents, err := os.ReadFile(filepath.Join(buildPath, "package.toml"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal(fmt.Sprintf("[buildpack]\nuri = \"%s\"\n\nother-toml", configDir)))
+		})
+
+		context("flatten", func() {
+			var packArgs []string
+
+			it.Before(func() {
+				mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+					packArgs = e.Args
+					return e.Command == "pack"
+				})).Return(nil)
+			})
+
+			it("does not flatten when auto and BP_FLATTEN_DISABLED is set", func() {
+				t.Setenv("BP_FLATTEN_DISABLED", "true")
+
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.BuildpackID = "some-id"
+				p.BuildpackPath = buildpackPath
+				p.Flatten = "auto"
+
+				Expect(p.BundleComposite(buildPath)).To(Succeed())
+				Expect(packArgs).NotTo(ContainElement("--flatten"))
+			})
+
+			it("flattens when true even if BP_FLATTEN_DISABLED is set", func() {
+				t.Setenv("BP_FLATTEN_DISABLED", "true")
+
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.BuildpackID = "some-id"
+				p.BuildpackPath = buildpackPath
+				p.Flatten = "true"
+
+				Expect(p.BundleComposite(buildPath)).To(Succeed())
+				Expect(packArgs).To(ContainElement("--flatten"))
+			})
+
+			it("does not flatten when false", func() {
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.BuildpackID = "some-id"
+				p.BuildpackPath = buildpackPath
+				p.Flatten = "false"
+
+				Expect(p.BundleComposite(buildPath)).To(Succeed())
+				Expect(packArgs).NotTo(ContainElement("--flatten"))
+			})
+
+			it("rejects an invalid value", func() {
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.BuildpackID = "some-id"
+				p.BuildpackPath = buildpackPath
+				p.Flatten = "yes"
+
+				Expect(p.BundleComposite(buildPath)).To(MatchError(`invalid flatten "yes", must be auto, true or false`))
+				mockExecutor.AssertNotCalled(t, "Execute", mock.Anything)
+			})
+		})
+	})
+
+	context("Bundle directory", func() {
+		var (
+			mockExecutor *mocks.Executor
+			path         string
+		)
+
+		it.Before(func() {
+			mockExecutor = &mocks.Executor{}
+			path = t.TempDir()
+
+			Expect(os.WriteFile(filepath.Join(path, "buildpack.toml"), []byte(`api = "0.10"
+[buildpack]
+id = "some-org/prebuilt"
+`), 0600)).To(Succeed())
+		})
+
+		it("packages the directory without compiling it", func() {
+			Expect(os.MkdirAll(filepath.Join(path, "bin"), 0755)).To(Succeed())
+
+			mockExecutor.On("Execute", mock.Anything).Return(nil)
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildID = "some-build-id"
+
+			Expect(p.BundleDirectory(path)).To(Succeed())
+			Expect(p.BuildpackID).To(Equal("some-org/prebuilt"))
+
+			Expect(mockExecutor.Calls).To(HaveLen(1))
+			e := mockExecutor.Calls[0].Arguments[0].(effect.Execution)
+			Expect(e.Command).To(Equal("pack"))
+			Expect(e.Dir).To(Equal(path))
+			Expect(e.Args).To(Equal([]string{
+				"buildpack", "package", "some-org/prebuilt",
+				"--pull-policy", "if-not-present",
+				"--target", "linux/amd64",
+				"--label", "io.paketo.libpak-tools.build=some-build-id",
+			}))
+		})
+
+		it("keeps a buildpack id that is set", func() {
+			Expect(os.MkdirAll(filepath.Join(path, "bin"), 0755)).To(Succeed())
+
+			mockExecutor.On("Execute", mock.Anything).Return(nil)
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-org/other"
+
+			Expect(p.BundleDirectory(path)).To(Succeed())
+			Expect(mockExecutor.Calls[0].Arguments[0].(effect.Execution).Args[2]).To(Equal("some-org/other"))
+		})
+
+		it("fails without a bin directory", func() {
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+
+			Expect(p.BundleDirectory(path)).To(MatchError(fmt.Sprintf("%s does not contain a bin directory", path)))
+			mockExecutor.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+
+		it("fails without a buildpack.toml", func() {
+			Expect(os.Remove(filepath.Join(path, "buildpack.toml"))).To(Succeed())
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+
+			Expect(p.BundleDirectory(path)).To(MatchError(ContainSubstring("unable to read")))
+			mockExecutor.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+	})
+
+	context("Is component", func() {
+		var path string
+
+		it.Before(func() {
+			path = t.TempDir()
+		})
+
+		it("is a component buildpack without main.go when buildpack.toml has no order", func() {
+			Expect(os.MkdirAll(filepath.Join(path, "bin"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(path, "bin", "build"), []byte("#!/usr/bin/env bash\n"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(path, "buildpack.toml"), []byte(`api = "0.10"
+[buildpack]
+id = "some-org/bash-buildpack"
+`), 0600)).To(Succeed())
+
+			p := packager.NewBundleBuildpackForTests(nil, nil)
+			p.BuildpackPath = path
+
+			Expect(p.IsComponent()).To(BeTrue())
+		})
+
+		it("is a component when there is an extension.toml", func() {
+			Expect(os.WriteFile(filepath.Join(path, "extension.toml"), []byte(`api = "0.10"
+[extension]
+id = "some-org/some-extension"
+`), 0600)).To(Succeed())
+
+			p := packager.NewBundleBuildpackForTests(nil, nil)
+			p.BuildpackPath = path
+
+			Expect(p.IsComponent()).To(BeTrue())
+		})
+
+		it("is a composite buildpack when buildpack.toml has an order", func() {
+			Expect(os.MkdirAll(filepath.Join(path, "cmd", "main"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(path, "cmd", "main", "main.go"), []byte("package main\n"), 0600)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(path, "buildpack.toml"), []byte(`api = "0.10"
+[buildpack]
+id = "some-org/composite"
+
+[[order]]
+  [[order.group]]
+    id = "some-org/bash-buildpack"
+    version = "1.0.0"
+`), 0600)).To(Succeed())
+
+			p := packager.NewBundleBuildpackForTests(nil, nil)
+			p.BuildpackPath = path
+
+			Expect(p.IsComponent()).To(BeFalse())
+		})
+
+		it("falls back to main.go when there is no buildpack.toml", func() {
+			p := packager.NewBundleBuildpackForTests(nil, nil)
+			p.BuildpackPath = path
+
+			Expect(p.IsComponent()).To(BeFalse())
+
+			Expect(os.MkdirAll(filepath.Join(path, "cmd", "main"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(path, "cmd", "main", "main.go"), []byte("package main\n"), 0600)).To(Succeed())
+
+			Expect(p.IsComponent()).To(BeTrue())
+		})
+
+		it("fails if buildpack.toml cannot be decoded", func() {
+			Expect(os.WriteFile(filepath.Join(path, "buildpack.toml"), []byte("not [valid toml"), 0600)).To(Succeed())
+
+			p := packager.NewBundleBuildpackForTests(nil, nil)
+			p.BuildpackPath = path
+
+			_, err := p.IsComponent()
+			Expect(err).To(MatchError(ContainSubstring("unable to decode")))
+		})
+	})
+
+	context("Execute", func() {
+		var (
+			buildpackPath string
+			mockExecutor  *mocks.Executor
+		)
+
+		it.Before(func() {
+			buildpackPath = t.TempDir()
+			mockExecutor = &mocks.Executor{}
+			t.Setenv("BP_ARCH", "amd64")
+
+			Expect(os.WriteFile(filepath.Join(buildpackPath, "package.toml"), []byte(""), 0600)).To(Succeed())
+
+			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "pack"
+			})).Return(nil)
+			mockExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "docker"
+			})).Return(nil)
+		})
+
+		it("cleans up images", func() {
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+			p.BuildpackPath = buildpackPath
+
+			Expect(p.Execute()).To(Succeed())
+			mockExecutor.AssertCalled(t, "Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "docker" && e.Args[0] == "image" && e.Args[1] == "ls"
+			}))
+		})
+
+		it("rejects a config dir for a component buildpack", func() {
+			configDir := t.TempDir()
+			Expect(os.WriteFile(filepath.Join(configDir, "buildpack.toml"), []byte("[buildpack]\nid = \"some-id\"\n"), 0600)).To(Succeed())
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+			p.BuildpackPath = buildpackPath
+			p.ConfigDir = configDir
+
+			Expect(p.Execute()).To(MatchError(ContainSubstring("config-dir is only supported for composite buildpacks")))
+			mockExecutor.AssertNotCalled(t, "Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "pack"
+			}))
+		})
+
+		it("only cleans up images with the build id pack labeled", func() {
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+			p.BuildpackPath = buildpackPath
+
+			Expect(p.Execute()).To(Succeed())
+			Expect(p.BuildID).NotTo(BeEmpty())
+			mockExecutor.AssertCalled(t, "Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "pack" && slices.Contains(e.Args, fmt.Sprintf("%s=%s", packager.BuildLabel, p.BuildID))
+			}))
+			mockExecutor.AssertCalled(t, "Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "docker" && e.Args[1] == "ls" &&
+					slices.Contains(e.Args, fmt.Sprintf("label=%s=%s", packager.BuildLabel, p.BuildID))
+			}))
+		})
+
+		it("does not inspect or clean up images when writing to an output file", func() {
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+			p.BuildpackPath = buildpackPath
+			p.OutputFile = filepath.Join(t.TempDir(), "some-id.cnb")
+
+			Expect(p.Execute()).To(Succeed())
+			Expect(p.ResultDigest).To(BeEmpty())
+			mockExecutor.AssertNotCalled(t, "Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "docker"
+			}))
+		})
+
+		context("digest inspection fails", func() {
+			var inspectExecutor *mocks.Executor
+
+			it.Before(func() {
+				inspectExecutor = &mocks.Executor{}
+				inspectExecutor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool {
+					return e.Command == "docker" && e.Args[1] == "inspect"
+				})).Return(fmt.Errorf("inspect-error"))
+				inspectExecutor.On("Execute", mock.Anything).Return(nil)
+			})
+
+			it("warns and still cleans up when the digest is not needed", func() {
+				progress := &bytes.Buffer{}
+
+				p := packager.NewBundleBuildpackForTests(inspectExecutor, nil)
+				p.BuildpackID = "some-id"
+				p.BuildpackPath = buildpackPath
+				p.Progress = progress
+
+				Expect(p.Execute()).To(Succeed())
+				Expect(p.ResultDigest).To(BeEmpty())
+				Expect(progress.String()).To(ContainSubstring("Warning: unable to inspect digest of some-id"))
+				inspectExecutor.AssertCalled(t, "Execute", mock.MatchedBy(func(e effect.Execution) bool {
+					return e.Command == "docker" && e.Args[0] == "image" && e.Args[1] == "ls"
+				}))
+			})
+
+			it("fails when the digest is recorded in a summary file", func() {
+				p := packager.NewBundleBuildpackForTests(inspectExecutor, nil)
+				p.BuildpackID = "some-id"
+				p.BuildpackPath = buildpackPath
+				p.SummaryFile = filepath.Join(t.TempDir(), "summary.json")
+
+				Expect(p.Execute()).To(MatchError(ContainSubstring("unable to inspect digest of some-id")))
+			})
+		})
+
+		context("summary file", func() {
+			var summaryFile string
+
+			it.Before(func() {
+				summaryFile = filepath.Join(t.TempDir(), "summary.json")
+			})
+
+			it("writes what was done", func() {
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.BuildpackID = "some-id"
+				p.BuildpackPath = buildpackPath
+				p.BuildpackVersion = "1.2.3"
+				p.SummaryFile = summaryFile
+
+				Expect(p.Execute()).To(Succeed())
+				Expect(os.ReadFile(summaryFile)).To(MatchJSON(`{
+					"buildpackId": "some-id",
+					"version": "1.2.3",
+					"kind": "composite",
+					"targets": ["linux/amd64"],
+					"published": false,
+					"image": "some-id",
+					"packaged": true,
+					"signed": false
+				}`))
+			})
+
+			it("writes the error when packaging fails", func() {
+				failingExecutor := &mocks.Executor{}
+				failingExecutor.On("Execute", mock.Anything).Return(fmt.Errorf("some-error"))
+
+				p := packager.NewBundleBuildpackForTests(failingExecutor, nil)
+				p.BuildpackID = "some-id"
+				p.BuildpackPath = buildpackPath
+				p.BuildpackVersion = "1.2.3"
+				p.SummaryFile = summaryFile
+
+				Expect(p.Execute()).To(MatchError(ContainSubstring("some-error")))
+				Expect(os.ReadFile(summaryFile)).To(MatchJSON(`{
+					"buildpackId": "some-id",
+					"version": "1.2.3",
+					"kind": "composite",
+					"targets": ["linux/amd64"],
+					"published": false,
+					"image": "some-id",
+					"packaged": false,
+					"signed": false,
+					"error": "unable to execute ` + "`pack buildpack package`" + ` command\nsome-error"
+				}`))
+			})
+
+			it("does not write a summary when it is not set", func() {
+				p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+				p.BuildpackID = "some-id"
+				p.BuildpackPath = buildpackPath
+
+				Expect(p.Execute()).To(Succeed())
+				Expect(summaryFile).NotTo(BeAnExistingFile())
+			})
+		})
+
+		it("skips cleaning up images when BP_SKIP_IMAGE_CLEANUP is set", func() {
+			t.Setenv("BP_SKIP_IMAGE_CLEANUP", "true")
+
+			p := packager.NewBundleBuildpackForTests(mockExecutor, nil)
+			p.BuildpackID = "some-id"
+			p.BuildpackPath = buildpackPath
+
+			Expect(p.Execute()).To(Succeed())
+			mockExecutor.AssertNotCalled(t, "Execute", mock.MatchedBy(func(e effect.Execution) bool {
+				return e.Command == "docker" && e.Args[0] == "image" && e.Args[1] == "ls"
+			}))
+		})
 	})
 }
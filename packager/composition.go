@@ -0,0 +1,125 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package packager
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/paketo-buildpacks/libpak-tools/carton"
+)
+
+// CompositionEntry is a child buildpack of a composite buildpack
+type CompositionEntry struct {
+	// ID is the buildpack id from the buildpack.toml order, or the image repository if the child is not in the order
+	ID string `json:"id"`
+
+	// Version is the version from the buildpack.toml order, or the image tag if the child is not in the order
+	Version string `json:"version,omitempty"`
+
+	// URI is the reference to the child in package.toml
+	URI string `json:"uri,omitempty"`
+
+	// Digest is the digest pinned in the package.toml URI
+	Digest string `json:"digest,omitempty"`
+}
+
+// Composition lists the child buildpacks of a composite buildpack from its buildpack.toml order and package.toml
+func (p *BundleBuildpack) Composition() ([]CompositionEntry, error) {
+	var bp struct {
+		Order []struct {
+			Group []struct {
+				ID      string `toml:"id"`
+				Version string `toml:"version"`
+			} `toml:"group"`
+		} `toml:"order"`
+	}
+
+	if err := decodeTOMLFile(filepath.Join(p.configDir(), "buildpack.toml"), &bp); err != nil {
+		return nil, err
+	}
+
+	var pkg struct {
+		Dependencies []struct {
+			URI string `toml:"uri"`
+		} `toml:"dependencies"`
+	}
+
+	packageToml := filepath.Join(p.configDir(), "package.toml")
+	if _, err := os.Stat(packageToml); err == nil {
+		if err := decodeTOMLFile(packageToml, &pkg); err != nil {
+			return nil, err
+		}
+	}
+
+	refs := []carton.ImageReference{}
+	for _, dep := range pkg.Dependencies {
+		refs = append(refs, carton.ParseImageReference(dep.URI))
+	}
+
+	var entries []CompositionEntry
+	used := map[int]bool{}
+	seen := map[string]bool{}
+	for _, order := range bp.Order {
+		for _, g := range order.Group {
+			if seen[g.ID] {
+				continue
+			}
+			seen[g.ID] = true
+
+			e := CompositionEntry{ID: g.ID, Version: g.Version}
+			for i, ref := range refs {
+				if !used[i] && strings.HasSuffix(ref.Repository, "/"+g.ID) {
+					used[i] = true
+					e.URI = ref.String()
+					e.Digest = ref.Digest
+					if e.Version == "" {
+						e.Version = ref.Tag
+					}
+					break
+				}
+			}
+
+			entries = append(entries, e)
+		}
+	}
+
+	for i, ref := range refs {
+		if !used[i] {
+			entries = append(entries, CompositionEntry{ID: ref.Repository, Version: ref.Tag, URI: ref.String(), Digest: ref.Digest})
+		}
+	}
+
+	return entries, nil
+}
+
+func decodeTOMLFile(path string, v interface{}) error {
+	c, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read %s\n%w", path, err)
+	}
+
+	if err := toml.Unmarshal(c, v); err != nil {
+		return fmt.Errorf("unable to decode %s\n%w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package packager_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libpak-tools/packager"
+)
+
+func testComposition(t *testing.T, _ spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		buildpackPath string
+	)
+
+	it.Before(func() {
+		buildpackPath = t.TempDir()
+	})
+
+	it("lists the children of a composite buildpack", func() {
+		Expect(os.WriteFile(filepath.Join(buildpackPath, "buildpack.toml"), []byte(`
+[buildpack]
+id = "paketo-buildpacks/java"
+
+[[order]]
+  [[order.group]]
+    id = "paketo-buildpacks/ca-certificates"
+    version = "3.6.3"
+
+  [[order.group]]
+    id = "paketo-buildpacks/bellsoft-liberica"
+    version = "10.4.2"
+
+[[order]]
+  [[order.group]]
+    id = "paketo-buildpacks/ca-certificates"
+    version = "3.6.3"
+`), 0600)).To(Succeed())
+
+		Expect(os.WriteFile(filepath.Join(buildpackPath, "package.toml"), []byte(`
+[[dependencies]]
+  uri = "docker://gcr.io/paketo-buildpacks/ca-certificates:3.6.3"
+
+[[dependencies]]
+  uri = "docker://gcr.io/paketo-buildpacks/bellsoft-liberica:10.4.2@sha256:abc"
+
+[[dependencies]]
+  uri = "docker://registry:5000/paketo-buildpacks/extra:1.0.0"
+`), 0600)).To(Succeed())
+
+		p := packager.NewBundleBuildpack()
+		p.BuildpackPath = buildpackPath
+
+		entries, err := p.Composition()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(Equal([]packager.CompositionEntry{
+			{
+				ID:      "paketo-buildpacks/ca-certificates",
+				Version: "3.6.3",
+				URI:     "docker://gcr.io/paketo-buildpacks/ca-certificates:3.6.3",
+			},
+			{
+				ID:      "paketo-buildpacks/bellsoft-liberica",
+				Version: "10.4.2",
+				URI:     "docker://gcr.io/paketo-buildpacks/bellsoft-liberica:10.4.2@sha256:abc",
+				Digest:  "sha256:abc",
+			},
+			{
+				ID:      "registry:5000/paketo-buildpacks/extra",
+				Version: "1.0.0",
+				URI:     "docker://registry:5000/paketo-buildpacks/extra:1.0.0",
+			},
+		}))
+	})
+
+	it("lists the order when there is no package.toml", func() {
+		Expect(os.WriteFile(filepath.Join(buildpackPath, "buildpack.toml"), []byte(`
+[[order]]
+  [[order.group]]
+    id = "paketo-buildpacks/ca-certificates"
+    version = "3.6.3"
+`), 0600)).To(Succeed())
+
+		p := packager.NewBundleBuildpack()
+		p.BuildpackPath = buildpackPath
+
+		entries, err := p.Composition()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(Equal([]packager.CompositionEntry{
+			{ID: "paketo-buildpacks/ca-certificates", Version: "3.6.3"},
+		}))
+	})
+
+	it("fails without a buildpack.toml", func() {
+		p := packager.NewBundleBuildpack()
+		p.BuildpackPath = buildpackPath
+
+		_, err := p.Composition()
+		Expect(err).To(MatchError(ContainSubstring("unable to read")))
+	})
+}
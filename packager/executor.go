@@ -0,0 +1,92 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package packager
+
+import (
+	"context"
+	"os/exec"
+
+	"github.com/paketo-buildpacks/libpak/v2/effect"
+)
+
+// ContextExecutor is an Executor that can also stop an execution when a context is done.
+type ContextExecutor interface {
+	effect.Executor
+
+	// ExecuteContext executes the command described in the Execution and kills it when ctx is done.
+	ExecuteContext(ctx context.Context, execution effect.Execution) error
+}
+
+// CommandContextExecutor delegates Execute to the wrapped Executor and runs ExecuteContext without a TTY using
+// exec.CommandContext.
+type CommandContextExecutor struct {
+	effect.Executor
+}
+
+// NewExecutor creates a ContextExecutor that wraps the default libpak Executor.
+func NewExecutor() CommandContextExecutor {
+	return CommandContextExecutor{Executor: effect.NewExecutor()}
+}
+
+func (CommandContextExecutor) ExecuteContext(ctx context.Context, execution effect.Execution) error {
+	// #nosec G204 -- this is a generic executor so this cannot apply
+	cmd := exec.CommandContext(ctx, execution.Command, execution.Args...)
+
+	if execution.Dir != "" {
+		cmd.Dir = execution.Dir
+	}
+
+	if len(execution.Env) > 0 {
+		cmd.Env = execution.Env
+	}
+
+	cmd.Stdin = execution.Stdin
+	cmd.Stdout = execution.Stdout
+	cmd.Stderr = execution.Stderr
+
+	return cmd.Run()
+}
+
+// executeContext runs the execution with a ContextExecutor if available. Other executors cannot be stopped, so the
+// execution is abandoned when ctx is done.
+func executeContext(ctx context.Context, executor effect.Executor, execution effect.Execution) error {
+	if ctx.Done() == nil {
+		return executor.Execute(execution)
+	}
+
+	if e, ok := executor.(ContextExecutor); ok {
+		if err := e.ExecuteContext(ctx, execution); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		return nil
+	}
+
+	result := make(chan error, 1)
+	go func() {
+		result <- executor.Execute(execution)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package packager_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/v2/effect"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libpak-tools/packager"
+)
+
+func testExecutor(t *testing.T, _ spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+	)
+
+	it("runs a command", func() {
+		buf := &bytes.Buffer{}
+
+		err := packager.NewExecutor().ExecuteContext(context.Background(), effect.Execution{
+			Command: "echo",
+			Args:    []string{"hello"},
+			Stdout:  buf,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(buf.String()).To(Equal("hello\n"))
+	})
+
+	it("kills a command when the context is done", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		err := packager.NewExecutor().ExecuteContext(ctx, effect.Execution{
+			Command: "sleep",
+			Args:    []string{"5"},
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+	})
+}
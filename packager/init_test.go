@@ -25,6 +25,9 @@ import (
 
 func TestUnit(t *testing.T) {
 	suite := spec.New("libpak-tools/packager", spec.Report(report.Terminal{}))
+	suite("Batch", testBatch)
 	suite("Buildpack", testBuildpack)
+	suite("Composition", testComposition)
+	suite("Executor", testExecutor)
 	suite.Run(t)
 }
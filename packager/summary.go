@@ -0,0 +1,106 @@
+/*
+ * Copyright 2018-2024 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package packager
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// BundleSummary records what packaging a buildpack did, for pipelines that would otherwise scrape its output
+type BundleSummary struct {
+	// BuildpackID is the id of the buildpack
+	BuildpackID string `json:"buildpackId"`
+
+	// Version is the version the buildpack was packaged as
+	Version string `json:"version"`
+
+	// Kind is `component` or `composite`, it is empty if packaging failed before it was known
+	Kind string `json:"kind,omitempty"`
+
+	// Targets are the targets passed to pack, they are empty when publishing since pack then uses the targets of the
+	// buildpack.toml
+	Targets []string `json:"targets,omitempty"`
+
+	// Published is true if the image was published to a registry
+	Published bool `json:"published"`
+
+	// Image is the name of the image, it is empty when the buildpack was written to OutputFile
+	Image string `json:"image,omitempty"`
+
+	// OutputFile is the .cnb file the buildpack was written to
+	OutputFile string `json:"outputFile,omitempty"`
+
+	// Packaged is true once pack has packaged the buildpack
+	Packaged bool `json:"packaged"`
+
+	// Digest is the digest of the published image, or the ID of the local image
+	Digest string `json:"digest,omitempty"`
+
+	// Signed is true if the image was signed
+	Signed bool `json:"signed"`
+
+	// Error is the error packaging failed with
+	Error string `json:"error,omitempty"`
+}
+
+// newSummary returns a summary of the configuration, before anything has run
+func (p *BundleBuildpack) newSummary() BundleSummary {
+	summary := BundleSummary{
+		BuildpackID: p.BuildpackID,
+		Version:     p.BuildpackVersion,
+		Published:   p.Publish,
+		OutputFile:  p.OutputFile,
+	}
+
+	if p.OutputFile == "" {
+		summary.Image = p.imageName()
+	}
+
+	if !p.Publish {
+		summary.Targets = []string{fmt.Sprintf("%s/%s", p.targetOS(), targetArch())}
+	}
+
+	return summary
+}
+
+// writeSummary writes summary to SummaryFile, if it is set, including the error packaging failed with. It returns
+// err, or the error writing the summary if packaging succeeded.
+func (p *BundleBuildpack) writeSummary(summary BundleSummary, err error) error {
+	if p.SummaryFile == "" {
+		return err
+	}
+
+	if err != nil {
+		summary.Error = err.Error()
+	}
+
+	c, mErr := json.MarshalIndent(summary, "", "  ")
+	if mErr == nil {
+		// #nosec G306 - the summary holds no secrets and is read by other pipeline steps
+		mErr = os.WriteFile(p.SummaryFile, append(c, '\n'), 0644)
+	}
+
+	if err != nil {
+		return err
+	} else if mErr != nil {
+		return fmt.Errorf("unable to write summary %s\n%w", p.SummaryFile, mErr)
+	}
+
+	return nil
+}